@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/cleaner"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// defaultConfigName is looked up in the home directory when --config is not set
+const defaultConfigName = ".docker-hub-cleaner.yaml"
+
+// rulesKey is the config file section with per-pattern retention rules
+const rulesKey = "rules"
+
+// rules are the per-pattern retention rules of the config file
+var rules []cleaner.Rule
+
+// configRule is a rule as written in the config file
+type configRule struct {
+	Pattern    string `mapstructure:"pattern"`
+	KeepDays   int    `mapstructure:"keep-days"`
+	KeepCount  int    `mapstructure:"keep-count"`
+	SortMethod string `mapstructure:"sort-method"`
+}
+
+// loadConfig applies the options of the YAML config file to every flag not
+// set on the command line (flags > environment > config file > defaults)
+// Keys are flag names, e.g. "keep-days: 30"; lists may be YAML sequences.
+// The rules section holds per-pattern retention rules
+func loadConfig(cmd *cobra.Command) error {
+	path := configFile
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(home, defaultConfigName)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+	}
+
+	viper.SetConfigFile(path)
+	viper.SetConfigType("yaml")
+	if err := viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	for _, key := range viper.AllKeys() {
+		if key == rulesKey {
+			if err := loadRules(); err != nil {
+				return fmt.Errorf("invalid rules in config file %s: %w", path, err)
+			}
+			continue
+		}
+		f := cmd.Flags().Lookup(key)
+		if f == nil || f.Name == "config" {
+			return fmt.Errorf("unknown option %q in config file %s", key, path)
+		}
+		if f.Changed || !viper.IsSet(key) {
+			continue
+		}
+		if err := setFlag(f, viper.Get(key)); err != nil {
+			return fmt.Errorf("invalid value for %q in config file %s: %w", key, path, err)
+		}
+	}
+	return nil
+}
+
+// loadRules reads the rules section, rejecting unknown rule options
+func loadRules() error {
+	var parsed []configRule
+	err := viper.UnmarshalKey(rulesKey, &parsed, func(c *mapstructure.DecoderConfig) {
+		c.ErrorUnused = true
+	})
+	if err != nil {
+		return err
+	}
+
+	rules = make([]cleaner.Rule, 0, len(parsed))
+	for _, r := range parsed {
+		rules = append(rules, cleaner.Rule(r))
+	}
+	return nil
+}
+
+// setFlag sets a flag from a config file value
+// A sequence sets the items of a list flag one by one (so regexes may
+// contain commas), or is comma-joined for other flags
+func setFlag(f *pflag.Flag, v any) error {
+	list, ok := v.([]any)
+	if !ok {
+		return f.Value.Set(fmt.Sprint(v))
+	}
+	items := make([]string, 0, len(list))
+	for _, item := range list {
+		items = append(items, fmt.Sprint(item))
+	}
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		return sv.Replace(items)
+	}
+	return f.Value.Set(strings.Join(items, ","))
+}
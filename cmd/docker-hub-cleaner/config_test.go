@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/cleaner"
+	"github.com/spf13/viper"
+)
+
+// readConfig loads a YAML config into the global viper instance
+func readConfig(t *testing.T, yaml string) {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.SetConfigType("yaml")
+	if err := viper.ReadConfig(strings.NewReader(yaml)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	readConfig(t, `
+rules:
+  - pattern: ^feature-
+    keep-count: 3
+  - pattern: ^v
+    keep-days: 90
+    sort-method: semver
+`)
+	if err := loadRules(); err != nil {
+		t.Fatal(err)
+	}
+	want := []cleaner.Rule{
+		{Pattern: "^feature-", KeepCount: 3},
+		{Pattern: "^v", KeepDays: 90, SortMethod: "semver"},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("rules = %+v, want %+v", rules, want)
+	}
+}
+
+func TestLoadRulesUnknownKey(t *testing.T) {
+	readConfig(t, `
+rules:
+  - pattern: ^feature-
+    keep-cout: 3
+`)
+	err := loadRules()
+	if err == nil || !strings.Contains(err.Error(), "keep-cout") {
+		t.Errorf("loadRules() error = %v, want the unknown key reported", err)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+var (
+	interactive bool
+
+	// promptMu serializes prompts across repository workers; prompts go to
+	// stderr so they never mix with a summary written to stdout
+	promptMu sync.Mutex
+	stdin    = bufio.NewReader(os.Stdin)
+)
+
+// checkInteractive verifies that --interactive can prompt before deleting
+func checkInteractive() error {
+	if dryRun {
+		return fmt.Errorf("--interactive has nothing to confirm with --dry-run")
+	}
+	if deleteRepo {
+		return fmt.Errorf("--interactive cannot be combined with --delete-repository (it asks for confirmation itself)")
+	}
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return fmt.Errorf("--interactive requires a terminal on stdin")
+	}
+	return nil
+}
+
+// confirmTags prints the tags about to be deleted from repo and asks
+// whether to delete all of them, none, or to decide tag by tag (y/n/q,
+// where q keeps the current tag and all remaining ones)
+func confirmTags(repo string, tags []api.Tag) ([]api.Tag, error) {
+	promptMu.Lock()
+	defer promptMu.Unlock()
+
+	w := os.Stderr
+	fmt.Fprintf(w, "\nTags to delete from %s (%d):\n", repo, len(tags))
+	for _, tag := range tags {
+		fmt.Fprintf(w, "  - %s (updated %s)\n", tag.Name, tag.LastUpdated.Format("2006-01-02"))
+	}
+
+	for {
+		answer, err := ask(w, fmt.Sprintf("Delete these %d tags? [a]ll, [p]er tag, [n]one: ", len(tags)))
+		if err != nil {
+			return nil, err
+		}
+		switch answer {
+		case "a", "all":
+			return tags, nil
+		case "n", "none", "":
+			return nil, nil
+		case "p", "per tag":
+			return confirmEach(w, repo, tags)
+		}
+	}
+}
+
+// confirmEach asks about every tag in turn
+func confirmEach(w io.Writer, repo string, tags []api.Tag) ([]api.Tag, error) {
+	var approved []api.Tag
+	for i := 0; i < len(tags); {
+		answer, err := ask(w, fmt.Sprintf("Delete %s:%s? [y/n/q] ", repo, tags[i].Name))
+		if err != nil {
+			return nil, err
+		}
+		switch answer {
+		case "y", "yes":
+			approved = append(approved, tags[i])
+		case "n", "no":
+		case "q", "quit":
+			return approved, nil
+		default:
+			continue
+		}
+		i++
+	}
+	return approved, nil
+}
+
+// ask prints a prompt and reads a lowercase answer; end of input is an error
+// so an abandoned prompt never deletes anything
+func ask(w io.Writer, prompt string) (string, error) {
+	fmt.Fprint(w, prompt)
+	line, err := stdin.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("no answer: %w", err)
+	}
+	return strings.ToLower(strings.TrimSpace(line)), nil
+}
+
+// confirmRepository lists the tags of a repository about to be deleted and
+// asks for its name to be typed; anything else declines
+func confirmRepository(repo string, tags []api.Tag) ([]api.Tag, error) {
+	promptMu.Lock()
+	defer promptMu.Unlock()
+
+	w := os.Stderr
+	fmt.Fprintf(w, "\nThis permanently deletes %s with all its tags and images (%d tags):\n", repo, len(tags))
+	for _, tag := range tags {
+		fmt.Fprintf(w, "  - %s (updated %s)\n", tag.Name, tag.LastUpdated.Format("2006-01-02"))
+	}
+
+	answer, err := ask(w, "Type the repository name to confirm: ")
+	if err != nil {
+		return nil, err
+	}
+	if answer != repo {
+		return nil, fmt.Errorf("typed %q, not %s", answer, repo)
+	}
+	return tags, nil
+}
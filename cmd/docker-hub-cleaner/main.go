@@ -2,16 +2,30 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/url"
 	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+	"github.com/ataraskov/docker-hub-cleaner/internal/auth"
 	"github.com/ataraskov/docker-hub-cleaner/internal/cleaner"
 	"github.com/ataraskov/docker-hub-cleaner/internal/filter"
 	"github.com/ataraskov/docker-hub-cleaner/internal/policy"
+	"github.com/ataraskov/docker-hub-cleaner/internal/reference"
+	"github.com/ataraskov/docker-hub-cleaner/internal/report"
 	sortpkg "github.com/ataraskov/docker-hub-cleaner/internal/sort"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -23,28 +37,124 @@ var (
 )
 
 var (
+	// Configuration file
+	configFile string
+
 	// Authentication flags
-	username   string
-	password   string
-	token      string
-	repository string
+	username        string
+	password        string
+	token           string
+	tokenType       string
+	useDockerConfig bool
+	registry        string
+	registryURL     string
+	repositories    []string
+	namespaces      []string
+	namespacesFile  string
+	repositoryRegex string
+	repoConcurrency int
 
 	// Retention policy flags
-	keepDays   int
-	keepCount  int
-	sortMethod string
+	keepDays         int
+	keepCount        int
+	keepSize         string
+	maxSize          string
+	policyMode       string
+	keepPulled       int
+	keepNeverPulled  bool
+	sortMethod       string
+	fallbackSort     string
+	semverTiebreak   string
+	prereleaseFirst  bool
+	countUnit        string
+	prunePrereleases bool
+	keepPrereleases  bool
+	pruneReleased    int
+	keepHighest      bool
+	keepPerMajor     int
+	keepPerMinor     int
+	groupBy          string
+	keepPattern      string
+	latestPerGroup   string
+	protect          []string
+	deletePattern    string
+	inUseFile        string
+	inUseURL         string
+	pinnedSource     string
+	maxDelete        int
+	deletePriority   string
+	ageField         string
+	timezone         string
 
 	// Filtering flags
 	tagPattern     string
 	excludePattern string
 	stripPrefix    string
+	tagNormalize   string
+	explainSort    bool
+	hasArch        string
+	lacksArch      string
+	versionRange   string
+	rangeInvalid   bool
+	labelSelector  string
+	tagLengthMin   int
+	tagLengthMax   int
+	tagCharset     string
+	minAge         string
+	tagStatus      string
 
 	// Execution flags
-	dryRun      bool
-	verbose     bool
-	concurrency int
+	dryRun        bool
+	simulateTime  bool
+	verbose       bool
+	concurrency   int
+	tagLimit      int
+	maxRetries    int
+	pageSize      int
+	stateFile     string
+	failFast      bool
+	deleteTimeout time.Duration
+	verify        bool
+	minRemaining  int
+	minKeep       int
+	allowEmpty    bool
+	allowLatest   bool
+	assertKeep    string
+	pruneUntagged bool
+	deleteRepo    bool
+	assumeYes     bool
+	confirm       []string
+	sharedDigests string
+	byManifest    bool
+
+	// Reporting flags
+	outputFormat  string
+	outputFile    string
+	webhookURL    string
+	metricsFile   string
+	githubOut     bool
+	showLargest   int
+	showRemaining bool
+	showRepoInfo  bool
+	onlyChanges   bool
+	realisticSize bool
+	snapshotDir   string
 )
 
+// flagAliases maps alternative flag names to their canonical flag
+func flagAliases(f *pflag.FlagSet, name string) pflag.NormalizedName {
+	switch name {
+	case "nonsemver-sort":
+		name = "fallback-sort"
+	case "keep-pulled-days":
+		name = "keep-pulled-within"
+	}
+	return pflag.NormalizedName(name)
+}
+
+// outMu serializes writes to the summary output across repository workers
+var outMu sync.Mutex
+
 var rootCmd = &cobra.Command{
 	Use:   "docker-hub-cleaner",
 	Short: "Clean up Docker Hub images based on retention policies",
@@ -55,239 +165,800 @@ Supports filtering by tags, retention by days or count, and dry-run mode.`,
 }
 
 func init() {
+	rootCmd.Flags().StringVar(&configFile, "config", "", "YAML file with flag values, keyed by flag name (default ~/"+defaultConfigName+" if present)")
+
 	// Authentication flags
 	rootCmd.Flags().StringVarP(&username, "username", "u", "", "Docker Hub username (or DOCKER_HUB_USERNAME env)")
 	rootCmd.Flags().StringVarP(&password, "password", "p", "", "Docker Hub password (or DOCKER_HUB_PASSWORD env)")
 	rootCmd.Flags().StringVarP(&token, "token", "t", "", "Personal Access Token (alternative to password)")
-	rootCmd.Flags().StringVarP(&repository, "repository", "r", "", "Repository name (format: username/repo)")
+	rootCmd.Flags().StringVar(&tokenType, "token-type", "jwt", "Token type: jwt, pat or oat (selects JWT or Bearer authorization)")
+	rootCmd.Flags().BoolVar(&useDockerConfig, "use-docker-config", false, "Read credentials saved by docker login from Docker's config.json")
+	rootCmd.Flags().StringVar(&registry, "registry", cleaner.RegistryDockerHub, "Registry backend: dockerhub, ghcr (GitHub Container Registry, token from --token or GITHUB_TOKEN env) or oci (registry at --registry-url)")
+	rootCmd.Flags().StringVar(&registryURL, "registry-url", "", "With --registry oci, base URL of the registry (e.g. https://registry.example.com)")
+	rootCmd.Flags().StringSliceVarP(&repositories, "repository", "r", nil, "Repository name (format: username/repo; repeatable to clean several)")
+	rootCmd.Flags().StringSliceVar(&namespaces, "namespace", nil, "Clean every repository in this namespace (repeatable; alternative to --repository)")
+	rootCmd.Flags().StringVar(&namespacesFile, "namespaces-file", "", "Also clean the namespaces listed in this file, one per line")
+	rootCmd.Flags().IntVar(&repoConcurrency, "repo-concurrency", 1, "With --namespace, number of repositories cleaned in parallel (all share one rate limiter)")
+	rootCmd.Flags().StringVar(&repositoryRegex, "repository-regex", "", "With --namespace, only clean repositories whose name matches this regex")
 
 	// Retention policy flags
 	rootCmd.Flags().IntVar(&keepDays, "keep-days", 0, "Keep images created within X days")
 	rootCmd.Flags().IntVar(&keepCount, "keep-count", 0, "Keep last X images")
-	rootCmd.Flags().StringVar(&sortMethod, "sort-method", "lexicographical", "Sorting method: lexicographical or semver")
+	rootCmd.Flags().StringVar(&maxSize, "max-size", "", "Keep the newest tags while their total size fits this budget (e.g., 50GB)")
+	rootCmd.Flags().StringVar(&keepSize, "keep-size", "", "With --policy-mode size-floor, size budget for kept tags (e.g., 50GB)")
+	rootCmd.Flags().StringVar(&policyMode, "policy-mode", cleaner.PolicyModeOR, "How retention policies combine: or (keep if any keeps), and (keep only if all keep) or size-floor (keep last X, then older tags within --keep-size)")
+	rootCmd.Flags().IntVar(&keepPulled, "keep-pulled-within", 0, "Keep images pulled within X days (needs tag_last_pulled, reported on some plans)")
+	rootCmd.Flags().BoolVar(&keepNeverPulled, "keep-never-pulled", false, "With --keep-pulled-within, keep tags without a last-pulled time instead of treating them as unpulled")
+	rootCmd.Flags().StringVar(&ageField, "age-field", string(api.AgeFieldLastUpdated), "Timestamp used for tag age: last_updated or last_pushed")
+	rootCmd.Flags().StringVar(&timezone, "timezone", "", "Count --keep-days from midnight in this IANA zone (e.g., Europe/Kyiv) instead of a rolling UTC cutoff")
+	rootCmd.Flags().StringVar(&countUnit, "count-unit", cleaner.CountUnitTags, "What --keep-count counts: tags or manifests (tags sharing a digest count once)")
+	rootCmd.Flags().StringVar(&sortMethod, "sort-method", cleaner.SortLexicographical, "Sorting method: lexicographical, semver or numeric-dotted (e.g., 20240615.1.2, 1.2.3.4)")
+	rootCmd.Flags().StringVar(&fallbackSort, "fallback-sort", cleaner.SortLexicographical, "With semver sorting, order non-semver tags by: lexicographical (or name), date or numeric-dotted")
+	rootCmd.Flags().StringVar(&semverTiebreak, "semver-tiebreak", string(sortpkg.TiebreakDate), "With semver sorting, order tags of equal precedence (e.g., 1.2.3+build1, 1.2.3+build2) by: date (newest first) or name")
+	rootCmd.Flags().BoolVar(&prereleaseFirst, "prerelease-first", false, "With semver sorting, order prereleases ahead of their release (1.2.3-rc1 counts as newer than 1.2.3)")
+	rootCmd.Flags().StringVar(&groupBy, "group-by", "", "Regex extracting a group key from tags; --keep-count applies per group (e.g., ^([a-z]+)-)")
+	rootCmd.Flags().StringVar(&keepPattern, "keep-pattern", "", "Regex pattern for tags to always keep (e.g., ^v[0-9]+\\.[0-9]+\\.[0-9]+$)")
+	rootCmd.Flags().StringArrayVar(&protect, "protect", nil, "Tag that is never deleted, whatever the other policies: an exact name (latest) or a regex (^release-.*); repeatable")
+	rootCmd.Flags().StringVar(&latestPerGroup, "keep-latest-per-group", "", "Regex whose first capture group names a tag family; keep only the newest tag of each family, tags not matching are left alone (e.g., ^feature-([a-z0-9-]+)-[0-9]+$)")
+	rootCmd.Flags().StringVar(&deletePattern, "delete-pattern", "", "Regex pattern for tags to delete directly, instead of --keep-days/--keep-count (e.g., ^pr-[0-9]+$)")
+	rootCmd.Flags().StringVar(&inUseFile, "in-use-file", "", "File listing deployed tags (one tag or repo:tag per line) that are never deleted")
+	rootCmd.Flags().StringVar(&inUseURL, "in-use-url", "", "URL returning deployed tags as JSON that are never deleted; the run fails if it is unreachable")
+	rootCmd.Flags().StringVar(&pinnedSource, "keep-digest-pinned-source", "", "File or URL listing digests pinned by consumers (image@sha256:...); their tags are never deleted, and nothing is deleted if it is unreadable")
+	rootCmd.Flags().IntVar(&keepPerMajor, "keep-per-major", 0, "With semver sorting, keep the newest X tags of each major version (1.x, 2.x, ...)")
+	rootCmd.Flags().IntVar(&keepPerMinor, "keep-per-minor", 0, "With semver sorting, keep the newest X tags of each major.minor version (1.2.x, 1.3.x, ...)")
+	rootCmd.Flags().BoolVar(&keepHighest, "keep-highest-semver", false, "Always keep the highest stable semver tag (the current release), however old")
+	rootCmd.Flags().BoolVar(&prunePrereleases, "prune-prereleases", false, "Always delete semver prerelease tags (e.g., 1.2.3-rc1), keep stable ones")
+	rootCmd.Flags().BoolVar(&keepPrereleases, "exclude-semver-prerelease", false, "Always keep semver prerelease tags (e.g., 1.2.3-rc1), whatever the retention policies say")
+	rootCmd.Flags().IntVar(&pruneReleased, "prune-released-prereleases", 0, "Delete semver prerelease tags (e.g., 1.2.3-rc1) once their stable release (1.2.3) is older than X days")
 
 	// Filtering flags
 	rootCmd.Flags().StringVar(&tagPattern, "tag-pattern", "", "Regex pattern for tags to include (e.g., ^dev-.*)")
 	rootCmd.Flags().StringVar(&excludePattern, "exclude-pattern", "", "Regex pattern for tags to exclude")
+	rootCmd.Flags().StringVar(&hasArch, "has-arch", "", "Only include tags with an image for this platform (e.g., linux/amd64)")
+	rootCmd.Flags().StringVar(&lacksArch, "lacks-arch", "", "Only include tags without an image for this platform (e.g., linux/arm64)")
+	rootCmd.Flags().IntVar(&tagLengthMin, "tag-length-min", 0, "Only include tags with at least this many characters")
+	rootCmd.Flags().IntVar(&tagLengthMax, "tag-length-max", 0, "Only include tags with at most this many characters (0 = no limit)")
+	rootCmd.Flags().StringVar(&minAge, "min-age", "", "Never consider tags younger than this for deletion, whatever the policies (e.g., 7d, 2w, 36h)")
+	rootCmd.Flags().StringVar(&tagCharset, "tag-charset", "", "Only include tags made entirely of: hex, digits or alnum")
+	rootCmd.Flags().StringVar(&labelSelector, "label-selector", "", "Only include tags whose image has these labels (e.g., env=ephemeral or expires); one registry lookup per image")
+	rootCmd.Flags().StringVar(&versionRange, "version-range", "", "Only include tags whose semver version is in range (e.g., \">=1.0.0 <2.0.0\")")
+	rootCmd.Flags().BoolVar(&rangeInvalid, "version-range-include-invalid", false, "With --version-range, also include tags that are not valid semver (e.g., latest, main)")
+	rootCmd.Flags().StringVar(&tagStatus, "status", filter.StatusAll, "Only include tags with this status: active, inactive or all")
 	rootCmd.Flags().StringVar(&stripPrefix, "strip-prefix", "", "Regex pattern to strip from tag before semver parsing")
+	rootCmd.Flags().StringVar(&tagNormalize, "tag-normalize", "", "Rewrite tag names for sorting/grouping only, as 'pattern=>replacement' (e.g., '^(release-|rel_)=>v')")
+	rootCmd.Flags().BoolVar(&explainSort, "explain-sort", false, "Log how each tag is stripped and parsed for semver sorting")
 
 	// Execution flags
 	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Report changes without deleting")
+	rootCmd.Flags().BoolVar(&simulateTime, "simulate-timing", false, "In dry-run, pace like a real run and log the estimated deletion time")
 	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
-	rootCmd.Flags().IntVar(&concurrency, "concurrency", 5, "Number of concurrent API requests")
-
-	// Mark required flags
-	_ = rootCmd.MarkFlagRequired("repository")
+	rootCmd.Flags().IntVar(&concurrency, "concurrency", api.DefaultRateBurst, "Maximum number of concurrent deletions (lowered automatically when throttled)")
+	rootCmd.Flags().StringVar(&sharedDigests, "protect-shared-digests", "", "Handle deletion candidates sharing a digest with a kept tag: warn or skip")
+	rootCmd.Flags().BoolVar(&byManifest, "by-manifest", false, "Evaluate retention per unique manifest and delete all of its tags together")
+	rootCmd.Flags().BoolVar(&pruneUntagged, "prune-untagged", false, "Also delete untagged manifests and report tags without images")
+	rootCmd.Flags().DurationVar(&deleteTimeout, "delete-timeout", 0, "Timeout for each tag deletion, e.g. 10s (0 = only the 30s HTTP timeout)")
+	rootCmd.Flags().BoolVar(&failFast, "fail-fast", false, "Abort on the first deletion error (default: continue and collect errors)")
+	rootCmd.Flags().BoolVar(&verify, "verify", false, "Re-fetch tags after deletion and warn about tags still listed")
+	rootCmd.Flags().IntVar(&minRemaining, "min-remaining", 1, "Abort if fewer than X tags would remain in the repository (0 = no floor)")
+	rootCmd.Flags().IntVar(&minKeep, "min-keep", 0, "Never delete the N newest tags (in sort order), whatever the policies decide")
+	rootCmd.Flags().IntVar(&maxDelete, "max-delete", 0, "Delete at most X tags per run and report the rest as deferred (0 = no limit)")
+	rootCmd.Flags().StringVar(&deletePriority, "delete-priority", cleaner.PriorityAge, "With --max-delete, which candidates to delete first: age (oldest) or size (largest)")
+	rootCmd.Flags().BoolVar(&deleteRepo, "delete-repository", false, "Delete the whole repository, ignoring retention policies and filters (irreversible)")
+	rootCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Confirm --delete-repository without prompting")
+	rootCmd.Flags().StringSliceVar(&confirm, "confirm", nil, "Re-type every --repository (or --namespace) value; the run fails unless they match exactly")
+	rootCmd.Flags().BoolVar(&interactive, "interactive", false, "List the tags to delete and ask for confirmation (all, per tag or none) before deleting")
+	rootCmd.Flags().BoolVar(&allowLatest, "allow-delete-latest", false, "Allow deleting tags that point at the image of the latest tag")
+	rootCmd.Flags().StringVar(&assertKeep, "assert-keep-file", "", "With --dry-run, fail unless exactly the tags listed in this file would remain")
+	rootCmd.Flags().BoolVar(&allowEmpty, "allow-empty", false, "Allow deleting every tag (disables --min-remaining)")
+	rootCmd.Flags().StringVar(&stateFile, "state-file", "", "Record deleted tags to this file and skip them when resuming an interrupted run")
+	rootCmd.Flags().IntVar(&maxRetries, "max-retries", api.DefaultMaxRetries, "Retries for a tag listing page after a network error or 5xx response")
+	rootCmd.Flags().IntVar(&tagLimit, "tag-limit", 0, "Stop fetching after X tags (0 = no limit; only safe with count/recent policies)")
+	rootCmd.Flags().IntVar(&pageSize, "page-size", api.DefaultPageSize, "Page size for tag listing (1-100)")
+	_ = rootCmd.Flags().MarkHidden("page-size")
+	rootCmd.Flags().SetNormalizeFunc(flagAliases)
+
+	// Reporting flags
+	rootCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text, table (one row per tag), json (one document at the end) or jsonl (stream tag actions as JSON lines)")
+	rootCmd.Flags().StringVar(&outputFile, "output-file", "", "Write the summary (in the --output format) to this file instead of stdout")
+	rootCmd.Flags().StringVar(&webhookURL, "webhook-url", "", "POST the run summary as JSON to this URL (best-effort)")
+	rootCmd.Flags().IntVar(&showLargest, "show-largest", 0, "Show the N largest tags selected for deletion")
+	rootCmd.Flags().BoolVar(&onlyChanges, "report-only-changes", false, "Print nothing unless tags were (or would be) deleted or errors occurred; info logs are hidden unless --verbose (for cron)")
+	rootCmd.Flags().BoolVar(&showRepoInfo, "show-repo-info", false, "Print repository description, pull count, stars and last update before the summary")
+	rootCmd.Flags().BoolVar(&showRemaining, "show-remaining", false, "List the tags that remain after deletion, in sort order")
+	rootCmd.Flags().BoolVar(&realisticSize, "realistic-size", false, "Estimate reclaimed size from layers not shared with kept tags (one API request per tag)")
+	rootCmd.Flags().StringVar(&snapshotDir, "snapshot-dir", "", "Store tag list snapshots here and report tags added/removed since the last run")
+	rootCmd.Flags().BoolVar(&githubOut, "github-output", false, "In GitHub Actions, write step outputs and a Markdown step summary")
+	rootCmd.Flags().StringVar(&metricsFile, "metrics-file", "", "Write Prometheus textfile metrics to this path after the run")
 
 	// Bind environment variables
 	_ = viper.BindEnv("username", "DOCKER_HUB_USERNAME")
 	_ = viper.BindEnv("password", "DOCKER_HUB_PASSWORD")
 	_ = viper.BindEnv("token", "DOCKER_HUB_TOKEN")
+
+	addPlanCommands()
 }
 
-func run(cmd *cobra.Command, args []string) error {
+func run(cmd *cobra.Command, args []string) (retErr error) {
+	// Command-line flags take precedence over the config file
+	if err := loadConfig(cmd); err != nil {
+		return err
+	}
+	if err := setupPlan(); err != nil {
+		return err
+	}
+
 	// Setup logger
 	logLevel := slog.LevelInfo
+	if onlyChanges {
+		// Routine progress would defeat the point of a silent no-op run
+		logLevel = slog.LevelWarn
+	}
 	if verbose {
 		logLevel = slog.LevelDebug
 	}
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+	// Keep stdout machine-readable when writing JSON to it
+	logOutput := os.Stdout
+	if structuredOutput() && outputFile == "" {
+		logOutput = os.Stderr
+	}
+
+	logger := slog.New(slog.NewTextHandler(logOutput, &slog.HandlerOptions{
 		Level: logLevel,
 	}))
 
 	// Get credentials from flags or environment
-	if username == "" {
-		username = viper.GetString("username")
+	// (the Docker Hub variables are never sent to another registry)
+	switch registry {
+	case cleaner.RegistryGHCR:
+		if token == "" {
+			token = os.Getenv("GITHUB_TOKEN")
+		}
+	case cleaner.RegistryOCI:
+	default:
+		if username == "" {
+			username = viper.GetString("username")
+		}
+		if password == "" {
+			password = viper.GetString("password")
+		}
+		if token == "" {
+			token = viper.GetString("token")
+		}
+	}
+
+	authenticator, err := selectAuthenticator(logger)
+	if err != nil {
+		return err
+	}
+
+	if namespacesFile != "" {
+		listed, err := cleaner.ReadTagList(namespacesFile)
+		if err != nil {
+			return fmt.Errorf("failed to read namespaces file: %w", err)
+		}
+		namespaces = append(namespaces, listed...)
+	}
+	namespaces = uniqueStrings(namespaces)
+	byNamespace := len(namespaces) > 0
+
+	if (len(repositories) == 0) == !byNamespace {
+		return fmt.Errorf("exactly one of --repository or --namespace/--namespaces-file must be provided")
+	}
+	if repositoryRegex != "" && !byNamespace {
+		return fmt.Errorf("--repository-regex requires --namespace")
+	}
+	if repoConcurrency < 1 {
+		return fmt.Errorf("--repo-concurrency must be at least 1")
+	}
+	for i, repo := range repositories {
+		normalized, err := normalizeRepository(repo)
+		if err != nil {
+			return err
+		}
+		if normalized != repo {
+			logger.Debug("Normalized repository name", "from", repo, "to", normalized)
+		}
+		repositories[i] = normalized
+	}
+	repositories = uniqueStrings(repositories)
+	multi := byNamespace || len(repositories) > 1
+
+	if len(confirm) > 0 {
+		if err := checkConfirmation(confirm, byNamespace); err != nil {
+			return err
+		}
+	}
+
+	var minAgeDur time.Duration
+	if minAge != "" {
+		minAgeDur, err = filter.ParseAge(minAge)
+		if err != nil {
+			return fmt.Errorf("invalid --min-age: %w", err)
+		}
 	}
-	if password == "" {
-		password = viper.GetString("password")
+
+	var keepSizeBytes int64
+	if keepSize != "" {
+		keepSizeBytes, err = policy.ParseSize(keepSize)
+		if err != nil {
+			return fmt.Errorf("invalid --keep-size: %w", err)
+		}
 	}
-	if token == "" {
-		token = viper.GetString("token")
+
+	var maxSizeBytes int64
+	if maxSize != "" {
+		maxSizeBytes, err = policy.ParseSize(maxSize)
+		if err != nil {
+			return fmt.Errorf("invalid --max-size: %w", err)
+		}
 	}
 
-	// Validate credentials
-	if token == "" && (username == "" || password == "") {
-		return fmt.Errorf("either --token or --username/--password must be provided")
+	var expectKept []string
+	if assertKeep != "" {
+		if !dryRun {
+			return fmt.Errorf("--assert-keep-file requires --dry-run")
+		}
+		expectKept, err = cleaner.ReadTagList(assertKeep)
+		if err != nil {
+			return fmt.Errorf("failed to read assert-keep file: %w", err)
+		}
 	}
 
-	// Validate repository format
-	if repository == "" {
-		return fmt.Errorf("--repository is required")
+	if interactive {
+		if err := checkInteractive(); err != nil {
+			return err
+		}
 	}
 
-	// Validate retention policies
-	if keepDays == 0 && keepCount == 0 {
-		return fmt.Errorf("at least one retention policy (--keep-days or --keep-count) must be specified")
+	if onlyChanges && outputFormat == "jsonl" {
+		return fmt.Errorf("--report-only-changes cannot be combined with --output jsonl (tag events are streamed as they happen)")
 	}
 
-	// Create API client
-	client := api.NewClient()
+	renderer, err := report.NewRenderer(outputFormat, report.RenderConfig{
+		DryRun:        dryRun,
+		PruneUntagged: pruneUntagged,
+		AllowLatest:   allowLatest,
+		ShowRepoInfo:  showRepoInfo,
+	})
+	if err != nil {
+		return err
+	}
 
-	// Authenticate
 	ctx := context.Background()
-	if token != "" {
-		client.AuthenticateWithToken(token)
-		logger.Info("Authenticated with token")
-	} else {
-		if err := client.Authenticate(ctx, username, password); err != nil {
-			return fmt.Errorf("authentication failed: %w", err)
+
+	opts := cleaner.Options{
+		Authenticator: authenticator,
+		Registry:      registry,
+		RegistryURL:   registryURL,
+
+		KeepDays:         keepDays,
+		KeepCount:        keepCount,
+		KeepSize:         keepSizeBytes,
+		MaxSize:          maxSizeBytes,
+		PolicyMode:       policyMode,
+		KeepPulledWithin: keepPulled,
+		KeepNeverPulled:  keepNeverPulled,
+		AgeField:         api.AgeField(ageField),
+		Timezone:         timezone,
+		SortMethod:       sortMethod,
+		FallbackSort:     fallbackSort,
+		CountUnit:        countUnit,
+		GroupBy:          groupBy,
+		KeepPattern:      keepPattern,
+		LatestPerGroup:   latestPerGroup,
+		Protect:          protect,
+		DeletePattern:    deletePattern,
+		InUseFile:        inUseFile,
+		InUseURL:         inUseURL,
+		PinnedDigests:    pinnedSource,
+		PrunePrereleases: prunePrereleases,
+		KeepPrereleases:  keepPrereleases,
+		PruneReleased:    pruneReleased,
+		KeepHighest:      keepHighest,
+		KeepPerMajor:     keepPerMajor,
+		KeepPerMinor:     keepPerMinor,
+		Rules:            rules,
+		MaxDelete:        maxDelete,
+		DeletePriority:   deletePriority,
+		SemverTiebreak:   semverTiebreak,
+		PrereleaseFirst:  prereleaseFirst,
+
+		TagPattern:     tagPattern,
+		ExcludePattern: excludePattern,
+		HasArch:        hasArch,
+		LacksArch:      lacksArch,
+		VersionRange:   versionRange,
+		RangeInvalid:   rangeInvalid,
+		LabelSelector:  labelSelector,
+		TagLengthMin:   tagLengthMin,
+		TagLengthMax:   tagLengthMax,
+		TagCharset:     tagCharset,
+		MinAge:         minAgeDur,
+		Status:         tagStatus,
+		StripPrefix:    stripPrefix,
+		TagNormalize:   tagNormalize,
+		ExplainSort:    explainSort,
+
+		DryRun:        dryRun,
+		SimulateTime:  simulateTime,
+		Verbose:       verbose,
+		Concurrency:   concurrency,
+		SharedDigests: sharedDigests,
+		ByManifest:    byManifest,
+		PruneUntagged: pruneUntagged,
+		FailFast:      failFast,
+		DeleteTimeout: deleteTimeout,
+		Verify:        verify,
+		MinRemaining:  minRemaining,
+		MinKeep:       minKeep,
+		AllowEmpty:    allowEmpty || minRemaining == 0, // the flag defaults to 1, so 0 was asked for
+		AllowLatest:   allowLatest,
+		StateFile:     stateFile,
+		SnapshotDir:   snapshotDir,
+		TagLimit:      tagLimit,
+		PageSize:      pageSize,
+		MaxRetries:    maxRetries,
+		ShowLargest:   showLargest,
+		ShowRemaining: showRemaining,
+		ShowRepoInfo:  showRepoInfo,
+		RealisticSize: realisticSize,
+
+		Logger: logger,
+	}
+
+	if deleteRepo {
+		if multi {
+			return fmt.Errorf("--delete-repository takes a single --repository")
 		}
-		logger.Info("Authenticated", "username", username)
+		return deleteRepository(ctx, withRepository(opts, repositories[0]))
 	}
 
-	// Setup filter
-	var tagFilter filter.TagFilter
-	var filters []filter.TagFilter
+	if interactive {
+		opts.Confirm = confirmTags
+	}
 
-	if tagPattern != "" {
-		f, err := filter.NewRegexFilter(tagPattern, false)
+	// The summary goes to stdout, or is streamed into --output-file, which is
+	// moved into place when the run ends (also when it fails)
+	var out io.Writer = os.Stdout
+	if outputFile != "" {
+		f, err := report.CreateFile(outputFile)
 		if err != nil {
-			return fmt.Errorf("invalid tag pattern: %w", err)
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		out = f
+		defer func() {
+			if err := f.Commit(); err != nil {
+				if retErr == nil {
+					retErr = fmt.Errorf("failed to write output file: %w", err)
+				} else {
+					logger.Error("Failed to write output file", "error", err)
+				}
+				return
+			}
+			logger.Info("Wrote summary", "path", outputFile)
+		}()
+	}
+
+	if outputFormat == "jsonl" {
+		// Each line names its repository, as concurrent repositories interleave
+		enc := json.NewEncoder(out)
+		opts.OnAction = func(a cleaner.TagAction) {
+			outMu.Lock()
+			defer outMu.Unlock()
+			if err := enc.Encode(a); err != nil {
+				logger.Warn("Failed to write tag action", "repository", a.Repository, "tag", a.Name, "error", err)
+			}
 		}
-		filters = append(filters, f)
-		logger.Info("Tag pattern filter enabled", "pattern", tagPattern)
 	}
 
-	if excludePattern != "" {
-		f, err := filter.NewRegexFilter(excludePattern, true)
+	repos := repositories
+	groups := namespaces
+	var unlisted []string // namespaces whose repositories could not be listed
+	if multi {
+		// One client for all repositories, so its rate limiter gates them all
+		opts.Client, err = cleaner.Connect(ctx, opts)
 		if err != nil {
-			return fmt.Errorf("invalid exclude pattern: %w", err)
+			return err
 		}
-		filters = append(filters, f)
-		logger.Info("Exclude pattern filter enabled", "pattern", excludePattern)
+	}
+	if byNamespace {
+		repos, unlisted, err = listRepositories(ctx, opts, logger)
+		if err != nil {
+			return err
+		}
+	} else {
+		groups = namespacesOf(repos)
 	}
 
-	if len(filters) > 0 {
-		tagFilter = filter.NewCompositeFilter(filters...)
+	// Clean repositories on up to repoConcurrency workers
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		reports []*report.Report
+		failed  []string
+		errs    []error
+		partial []error // individual failures of repositories that completed
+	)
+	sem := make(chan struct{}, repoConcurrency)
+	for _, repo := range repos {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(opts cleaner.Options) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			rep, err := cleanRepository(ctx, out, renderer, opts, expectKept, logger)
+
+			mu.Lock()
+			defer mu.Unlock()
+			var pf *cleaner.PartialFailureError
+			if errors.As(err, &pf) {
+				partial = append(partial, pf.Errors...)
+			} else if err != nil {
+				if multi {
+					logger.Error("Failed to clean repository", "repository", opts.Repository, "error", err)
+				}
+				failed = append(failed, opts.Repository)
+				errs = append(errs, err)
+				return
+			}
+			reports = append(reports, rep)
+		}(withRepository(opts, repo))
 	}
+	wg.Wait()
+
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Repository < reports[j].Repository })
+	sort.Strings(failed)
 
-	// Setup sorter
-	var sorter sortpkg.TagSorter
-	switch sortMethod {
-	case "lexicographical":
-		sorter = sortpkg.NewLexicographicalSorter()
-		logger.Info("Using lexicographical sorting")
-	case "semver":
-		s, err := sortpkg.NewSemverSorter(stripPrefix)
+	// Write metrics for node_exporter's textfile collector
+	if metricsFile != "" && len(reports) > 0 {
+		if err := report.WriteMetricsFile(metricsFile, reports...); err != nil {
+			return fmt.Errorf("failed to write metrics: %w", err)
+		}
+		logger.Debug("Wrote metrics", "path", metricsFile)
+	}
+
+	// Expose results to later workflow steps, failed repositories included
+	if githubOut && (len(reports) > 0 || len(failed) > 0) {
+		ok, err := report.WriteGitHub(dryRun, failed, reports...)
 		if err != nil {
-			return fmt.Errorf("invalid strip-prefix pattern: %w", err)
+			return err
 		}
-		sorter = s
-		logger.Info("Using semver sorting")
-		if stripPrefix != "" {
-			logger.Info("Strip prefix enabled", "pattern", stripPrefix)
+		if !ok {
+			logger.Warn("--github-output ignored: GITHUB_OUTPUT and GITHUB_STEP_SUMMARY are not set")
 		}
-	default:
-		return fmt.Errorf("invalid sort method: %s (must be 'lexicographical' or 'semver')", sortMethod)
 	}
 
-	// Fetch and sort tags first (needed for count policy)
-	logger.Info("Fetching tags for policy evaluation", "repository", repository)
-	allTags, err := client.ListTags(ctx, repository)
-	if err != nil {
-		return fmt.Errorf("failed to list tags: %w", err)
+	quiet := onlyChanges && len(failed) == 0 && len(unlisted) == 0 && !anyChanges(reports)
+	if multi && !structuredOutput() && !quiet {
+		report.WriteNamespaceSummary(out, groups, dryRun, reports, failed, unlisted)
+	}
+	if f, ok := renderer.(report.Flusher); ok {
+		if err := f.Flush(out); err != nil {
+			return err
+		}
+	}
+
+	if !multi && len(errs) > 0 {
+		return errs[0]
 	}
 
-	// Apply filters before sorting for count policy
-	if tagFilter != nil {
-		allTags = filter.FilterTags(allTags, tagFilter)
+	if planning {
+		if err := writePlan(logger, len(failed) > 0 || len(unlisted) > 0); err != nil {
+			return err
+		}
 	}
 
-	// Sort tags
-	sortedTags := sorter.Sort(allTags)
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d repositories failed", len(failed), len(repos))
+	}
+	if len(unlisted) > 0 {
+		return fmt.Errorf("%d of %d namespaces could not be listed", len(unlisted), len(namespaces))
+	}
+	if len(partial) > 0 {
+		return &cleaner.PartialFailureError{Errors: partial}
+	}
 
-	// Setup retention policy
-	var policies []policy.RetentionPolicy
+	return nil
+}
 
-	if keepDays > 0 {
-		policies = append(policies, policy.NewDaysRetentionPolicy(keepDays))
-		logger.Info("Days retention policy enabled", "days", keepDays)
+// deleteRepository deletes the whole repository after confirmation
+// Without --yes or --confirm the repository's tags are listed and its name
+// must be typed on an interactive terminal
+func deleteRepository(ctx context.Context, opts cleaner.Options) error {
+	if !dryRun && !assumeYes && len(confirm) == 0 {
+		if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return fmt.Errorf("--delete-repository requires --yes when not run interactively")
+		}
+		opts.Confirm = confirmRepository
 	}
 
-	if keepCount > 0 {
-		// Use sorted tags for count policy
-		policies = append(policies, policy.NewCountRetentionPolicy(keepCount, sortedTags))
-		logger.Info("Count retention policy enabled", "count", keepCount)
+	tags, err := cleaner.DeleteRepository(ctx, opts)
+	if err != nil {
+		return err
 	}
 
-	var retentionPolicy policy.RetentionPolicy
-	if len(policies) == 1 {
-		retentionPolicy = policies[0]
+	if dryRun {
+		fmt.Printf("Would delete repository %s (%d tags). Run without --dry-run to delete it.\n", opts.Repository, tags)
 	} else {
-		// Use OR mode: keep if ANY policy says to keep
-		retentionPolicy = policy.NewCompositePolicy(policy.PolicyModeOR, policies...)
-		logger.Info("Using OR policy mode (keep if ANY policy matches)")
-	}
-
-	// Create cleaner
-	c := cleaner.NewCleaner(cleaner.Config{
-		Client:  client,
-		Filter:  tagFilter,
-		Policy:  retentionPolicy,
-		Sorter:  sorter,
-		DryRun:  dryRun,
-		Logger:  logger,
-		Verbose: verbose,
-	})
+		fmt.Printf("Deleted repository %s (%d tags).\n", opts.Repository, tags)
+	}
+	return nil
+}
 
-	// Run cleaner
-	if dryRun {
-		logger.Info("=== DRY RUN MODE - No tags will be deleted ===")
+// normalizeRepository turns the accepted spellings of a repository into
+// the name the registry uses (see reference.Repository for Docker Hub)
+func normalizeRepository(s string) (string, error) {
+	repo := strings.TrimSuffix(strings.TrimSpace(s), "/")
+	repo = strings.TrimPrefix(repo, "https://")
+	repo = strings.TrimPrefix(repo, "http://")
+
+	switch registry {
+	case cleaner.RegistryGHCR:
+		return normalizePackage(s, repo)
+	case cleaner.RegistryOCI:
+		return normalizeOCIRepository(s, repo)
 	}
+	return reference.Repository(s)
+}
 
-	result, err := c.Clean(ctx, repository)
-	if err != nil {
-		return fmt.Errorf("cleaning failed: %w", err)
+// packageName matches a GHCR owner/package name; package names may
+// contain further path segments
+var packageName = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]*[a-z0-9])?(?:/[a-z0-9]+(?:[._-][a-z0-9]+)*)+$`)
+
+// normalizePackage turns a GHCR image reference into owner/package,
+// stripping a leading ghcr.io host (repo is s without the URL scheme)
+func normalizePackage(s, repo string) (string, error) {
+	repo = strings.ToLower(strings.TrimPrefix(repo, "ghcr.io/"))
+	if strings.ContainsAny(repo, ":@") {
+		return "", fmt.Errorf("invalid repository %q: remove the tag or digest", s)
+	}
+	if !packageName.MatchString(repo) {
+		return "", fmt.Errorf("invalid repository %q: expected owner/package (lowercase letters, digits, '.', '_' or '-')", s)
+	}
+	return repo, nil
+}
+
+// ociRepositoryName matches a repository name of the OCI distribution spec
+var ociRepositoryName = regexp.MustCompile(`^[a-z0-9]+(?:(?:\.|_|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:\.|_|__|-+)[a-z0-9]+)*)*$`)
+
+// normalizeOCIRepository turns an image reference on the --registry-url
+// registry into its repository name, stripping a leading registry host
+// (repo is s without the URL scheme)
+func normalizeOCIRepository(s, repo string) (string, error) {
+	if u, err := url.Parse(registryURL); err == nil && u.Host != "" {
+		repo = strings.TrimPrefix(repo, u.Host+"/")
+	}
+	if strings.ContainsAny(repo, ":@") {
+		return "", fmt.Errorf("invalid repository %q: remove the tag or digest (or a host other than --registry-url)", s)
+	}
+	if !ociRepositoryName.MatchString(repo) {
+		return "", fmt.Errorf("invalid repository %q: expected a repository path (lowercase letters, digits, '.', '_', '-' and '/')", s)
+	}
+	return repo, nil
+}
+
+// selectAuthenticator picks the credential source: a token, username and
+// password, or the credentials saved by `docker login`
+func selectAuthenticator(logger *slog.Logger) (api.Authenticator, error) {
+	switch {
+	case token != "":
+		return api.NewTokenAuth(token, api.TokenType(tokenType)), nil
+	case username != "" && password != "":
+		return api.NewPasswordAuth(username, password), nil
+	case useDockerConfig && registry != cleaner.RegistryDockerHub:
+		return nil, fmt.Errorf("--use-docker-config only reads Docker Hub credentials")
+	case useDockerConfig:
+		u, p, err := auth.FromDockerConfig()
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Using credentials from Docker config", "username", u)
+		return api.NewPasswordAuth(u, p), nil
+	case registry == cleaner.RegistryOCI:
+		// Self-hosted registries often allow anonymous access
+		return nil, nil
+	}
+	return nil, fmt.Errorf("either --token or --username/--password must be provided")
+}
+
+// cleanRepository runs the cleaner for one repository, prints its summary
+// to w and posts it to the webhook
+// With expectKept set, it fails unless exactly those tags remain
+func cleanRepository(ctx context.Context, w io.Writer, r report.Renderer, opts cleaner.Options, expectKept []string, logger *slog.Logger) (*report.Report, error) {
+	// On partial failure the summary is still printed and err returned last
+	result, err := runRepository(ctx, opts)
+	var partial *cleaner.PartialFailureError
+	if err != nil && !errors.As(err, &partial) {
+		return nil, err
+	}
+
+	rep := report.New(opts.Repository, dryRun, result)
+
+	stats := result.APIStats
+	logger.Debug("API usage", "repository", opts.Repository,
+		"requests", stats.Requests, "deletes", stats.Deletes, "retries", stats.Retries,
+		"rate_limited", stats.RateLimited, "failures", stats.Failures,
+		"request_time", stats.RequestTime.Round(time.Millisecond), "delete_time", stats.DeleteTime.Round(time.Millisecond),
+		"limiter_wait", stats.LimiterWait.Round(time.Millisecond), "backoff_wait", stats.BackoffWait.Round(time.Millisecond))
+
+	if onlyChanges && !hasChanges(result) {
+		logger.Debug("Nothing changed; summary suppressed", "repository", opts.Repository)
+	} else if err := render(w, r, result); err != nil {
+		return nil, err
+	}
+
+	// Notify webhook (best-effort)
+	if webhookURL != "" {
+		if err := report.PostWebhook(ctx, webhookURL, rep); err != nil {
+			logger.Warn("Failed to post summary to webhook", "error", err)
+		} else {
+			logger.Debug("Posted summary to webhook")
+		}
+	}
+
+	if expectKept != nil {
+		expected, err := cleaner.TagsFor(expectKept, opts.Repository)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --assert-keep-file: %w", err)
+		}
+		if err := assertRemaining(w, expected, result); err != nil {
+			return nil, err
+		}
 	}
 
-	// Print summary
-	fmt.Println("\n" + "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Println("SUMMARY")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Printf("Repository:       %s\n", repository)
-	fmt.Printf("Total tags:       %d\n", result.TotalTags)
-	fmt.Printf("After filtering:  %d\n", result.FilteredTags)
-	fmt.Printf("Tags to keep:     %d\n", result.KeptTags)
-	fmt.Printf("Tags %s:  %d\n", map[bool]string{true: "would delete", false: "deleted"}[dryRun], len(result.DeletedTags))
+	return rep, err
+}
 
-	if len(result.DeletedTags) > 0 {
-		fmt.Printf("Disk space:       %s\n", formatSize(result.ReclaimedSize))
+// assertRemaining compares the remaining tags with the expected list and
+// prints the differences
+func assertRemaining(w io.Writer, expected []string, result *cleaner.CleanResult) error {
+	diff := cleaner.DiffRemaining(expected, result)
+	if diff.Empty() {
+		return nil
 	}
 
-	if len(result.Errors) > 0 {
-		fmt.Printf("Errors:           %d\n", len(result.Errors))
-		for _, err := range result.Errors {
-			fmt.Printf("  - %s\n", err)
+	if !structuredOutput() {
+		outMu.Lock()
+		fmt.Fprintf(w, "\nRemaining tags of %s differ from --assert-keep-file:\n", result.Repository)
+		for _, name := range diff.UnexpectedDeletions {
+			fmt.Fprintf(w, "  - %s (expected to remain)\n", name)
+		}
+		for _, name := range diff.UnexpectedSurvivals {
+			fmt.Fprintf(w, "  + %s (not expected to remain)\n", name)
 		}
+		outMu.Unlock()
 	}
 
-	if dryRun && len(result.DeletedTags) > 0 {
-		fmt.Println("\nRun without --dry-run to execute deletion.")
+	return fmt.Errorf("keep assertion failed: %d unexpected deletions, %d unexpected survivals",
+		len(diff.UnexpectedDeletions), len(diff.UnexpectedSurvivals))
+}
+
+// render writes a repository's summary
+// Concurrent repository workers share w, so whole summaries are serialized
+func render(w io.Writer, r report.Renderer, result *cleaner.CleanResult) error {
+	outMu.Lock()
+	defer outMu.Unlock()
+	return r.Render(w, result)
+}
+
+// structuredOutput reports whether the output format is JSON, which must
+// not be mixed with logs or human-readable text
+func structuredOutput() bool {
+	return outputFormat == "json" || outputFormat == "jsonl"
+}
+
+// hasChanges reports whether a run deleted (or would delete) anything or hit errors
+func hasChanges(result *cleaner.CleanResult) bool {
+	return len(result.DeletedTags) > 0 || len(result.Untagged) > 0 || len(result.Errors) > 0
+}
+
+// anyChanges reports whether any repository of a namespace run has changes
+func anyChanges(reports []*report.Report) bool {
+	for _, rep := range reports {
+		if len(rep.DeletedTags) > 0 || len(rep.Untagged) > 0 || len(rep.Errors) > 0 {
+			return true
+		}
 	}
+	return false
+}
 
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+// checkConfirmation verifies that --confirm repeats the targets, in any
+// order: every repository (in any accepted spelling), or in namespace mode
+// every namespace
+func checkConfirmation(values []string, byNamespace bool) error {
+	want := repositories
+	if byNamespace {
+		want = namespaces
+	}
 
+	got := slices.Clone(values)
+	if !byNamespace {
+		for i, v := range got {
+			if normalized, err := normalizeRepository(v); err == nil {
+				got[i] = normalized
+			}
+		}
+	}
+	got = uniqueStrings(got)
+	slices.Sort(got)
+	if !slices.Equal(got, slices.Sorted(slices.Values(want))) {
+		flag := "--repository"
+		if byNamespace {
+			flag = "--namespace"
+		}
+		return fmt.Errorf("--confirm %q does not match %s %q, nothing was deleted",
+			strings.Join(values, ","), flag, strings.Join(want, ","))
+	}
 	return nil
 }
 
-func formatSize(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
+// namespacesOf returns the namespaces of repos, in first-seen order
+func namespacesOf(repos []string) []string {
+	var ns []string
+	for _, repo := range repos {
+		name, _, _ := strings.Cut(repo, "/")
+		ns = append(ns, name)
+	}
+	return uniqueStrings(ns)
+}
+
+// listRepositories lists the matching repositories of every namespace
+// A namespace that cannot be listed is logged and returned in unlisted so
+// the others are still cleaned; it fails only if nothing was listed
+func listRepositories(ctx context.Context, opts cleaner.Options, logger *slog.Logger) (repos, unlisted []string, err error) {
+	var errs []error
+	for _, ns := range namespaces {
+		names, err := cleaner.ListRepositories(ctx, opts, ns, repositoryRegex)
+		if err != nil {
+			logger.Error("Failed to list repositories", "namespace", ns, "error", err)
+			unlisted = append(unlisted, ns)
+			errs = append(errs, err)
+			continue
+		}
+		logger.Info("Repositories matched", "namespace", ns, "count", len(names))
+		repos = append(repos, names...)
 	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+
+	if len(unlisted) == len(namespaces) {
+		return nil, nil, errors.Join(errs...)
 	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+	if len(repos) == 0 && len(unlisted) == 0 {
+		return nil, nil, fmt.Errorf("no repositories in %s match %q", strings.Join(namespaces, ", "), repositoryRegex)
+	}
+	return repos, unlisted, nil
+}
+
+// uniqueStrings returns values without duplicates, in first-seen order
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var unique []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
 }
 
+// withRepository returns a copy of opts for another repository
+func withRepository(opts cleaner.Options, repo string) cleaner.Options {
+	opts.Repository = repo
+	return opts
+}
+
+// exitPartialFailure is the exit code of runs that completed with failures
+const exitPartialFailure = 2
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		var partial *cleaner.PartialFailureError
+		if errors.As(err, &partial) {
+			os.Exit(exitPartialFailure)
+		}
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/cleaner"
+	"github.com/ataraskov/docker-hub-cleaner/internal/plan"
+	"github.com/spf13/cobra"
+)
+
+var (
+	planOut  string
+	planning bool       // plan command: a dry run recorded in planOut
+	applying *plan.Plan // apply command: the plan being applied
+
+	// planned collects the plan of each repository while planning
+	planMu  sync.Mutex
+	planned *plan.Plan
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Record the tags a run would delete in a plan file for review",
+	Long: `Runs like --dry-run with the same flags and writes the tags that would be
+deleted (names, digests and sizes) to a plan file. "apply" deletes exactly
+those tags later, and refuses if a repository changed in between.`,
+	Args: cobra.NoArgs,
+	RunE: runPlan,
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply PLAN",
+	Short: "Delete exactly the tags recorded in a plan file",
+	Long: `Deletes the tags recorded by "plan", refusing any repository whose tags
+(names or digests) changed since the plan was made. Repositories and the
+registry come from the plan; retention and filter flags are not used.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runApply,
+}
+
+// addPlanCommands registers plan and apply, which share the root command's flags
+func addPlanCommands() {
+	planCmd.Flags().StringVar(&planOut, "out", "plan.json", "Write the plan to this file")
+	for _, cmd := range []*cobra.Command{planCmd, applyCmd} {
+		cmd.Flags().SetNormalizeFunc(flagAliases)
+		cmd.Flags().AddFlagSet(rootCmd.Flags())
+		rootCmd.AddCommand(cmd)
+	}
+}
+
+// runPlan makes a dry run and records what it would delete in a plan file
+func runPlan(cmd *cobra.Command, args []string) error {
+	planning = true
+	return run(cmd, args)
+}
+
+// runApply deletes the tags recorded in a plan file
+func runApply(cmd *cobra.Command, args []string) error {
+	p, err := plan.Read(args[0])
+	if err != nil {
+		return err
+	}
+	if len(p.Repositories) == 0 {
+		fmt.Println("The plan deletes nothing.")
+		return nil
+	}
+
+	applying = p
+	return run(cmd, args)
+}
+
+// setupPlan adjusts the run's flags for the plan and apply commands
+func setupPlan() error {
+	switch {
+	case planning:
+		if deleteRepo || tagLimit > 0 || pruneUntagged {
+			return fmt.Errorf("plan cannot be combined with --delete-repository, --tag-limit or --prune-untagged")
+		}
+		dryRun = true
+		planned = plan.New(registry, registryURL)
+	case applying != nil:
+		if deleteRepo {
+			return fmt.Errorf("apply cannot be combined with --delete-repository")
+		}
+		// The plan names the registry and the repositories
+		registry, registryURL = applying.Registry, applying.RegistryURL
+		repositories, namespaces, namespacesFile, repositoryRegex = applying.Names(), nil, "", ""
+	}
+	return nil
+}
+
+// runRepository cleans a repository (recording the result while planning),
+// or applies its part of the plan
+func runRepository(ctx context.Context, opts cleaner.Options) (*cleaner.CleanResult, error) {
+	if applying != nil {
+		repo, _ := applying.Find(opts.Repository)
+		return cleaner.Apply(ctx, opts, repo)
+	}
+
+	result, err := cleaner.Run(ctx, opts)
+	if planning && err == nil && len(result.DeletedTags) > 0 {
+		planMu.Lock()
+		planned.Add(plan.NewRepository(opts.Repository, result.Fetched, result.DeletedTags))
+		planMu.Unlock()
+	}
+	return result, err
+}
+
+// writePlan writes the collected plan, unless it is incomplete because
+// some repositories could not be planned
+func writePlan(logger *slog.Logger, incomplete bool) error {
+	if incomplete {
+		logger.Error("Plan not written: some repositories could not be planned", "path", planOut)
+		return nil
+	}
+
+	if err := plan.Write(planOut, planned); err != nil {
+		return err
+	}
+
+	tags := 0
+	for _, r := range planned.Repositories {
+		tags += len(r.Tags)
+	}
+	logger.Info("Wrote plan; review it, then run apply", "path", planOut,
+		"repositories", len(planned.Repositories), "tags", tags)
+	return nil
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Authenticator supplies credentials to a Client
+type Authenticator interface {
+	Authenticate(ctx context.Context, c *Client) error
+	Name() string
+}
+
+// PasswordAuth logs in with a username and password to obtain a JWT
+type PasswordAuth struct {
+	username string
+	password string
+}
+
+// NewPasswordAuth creates a new password authenticator
+func NewPasswordAuth(username, password string) *PasswordAuth {
+	return &PasswordAuth{
+		username: username,
+		password: password,
+	}
+}
+
+// Authenticate logs in and stores the session token on the client
+func (a *PasswordAuth) Authenticate(ctx context.Context, c *Client) error {
+	loginReq := LoginRequest{
+		Username: a.username,
+		Password: a.password,
+	}
+
+	body, err := json.Marshal(loginReq)
+	if err != nil {
+		return fmt.Errorf("failed to marshal login request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/users/login/", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return NewAPIError(resp.StatusCode, "/users/login/", string(bodyBytes))
+	}
+
+	var loginResp LoginResponse
+	if err := json.NewDecoder(resp.Body).Decode(&loginResp); err != nil {
+		return fmt.Errorf("failed to decode login response: %w", err)
+	}
+
+	c.token = loginResp.Token
+	c.authScheme = "JWT"
+	c.username = a.username
+	c.password = a.password
+	return nil
+}
+
+// Name returns a description of the credentials for logging
+func (a *PasswordAuth) Name() string {
+	return "password (" + a.username + ")"
+}
+
+// TokenAuth uses an access token directly, without a login request
+type TokenAuth struct {
+	token     string
+	tokenType TokenType
+}
+
+// NewTokenAuth creates a new token authenticator
+func NewTokenAuth(token string, tokenType TokenType) *TokenAuth {
+	return &TokenAuth{
+		token:     token,
+		tokenType: tokenType,
+	}
+}
+
+// Authenticate stores the token on the client, selecting the matching
+// Authorization scheme (JWT or Bearer)
+func (a *TokenAuth) Authenticate(ctx context.Context, c *Client) error {
+	switch a.tokenType {
+	case TokenTypeJWT:
+		c.authScheme = "JWT"
+	case TokenTypePAT, TokenTypeOAT:
+		c.authScheme = "Bearer"
+	default:
+		return fmt.Errorf("unsupported token type: %s", a.tokenType)
+	}
+
+	c.token = a.token
+	return nil
+}
+
+// TokenType returns the type of the token
+func (a *TokenAuth) TokenType() TokenType {
+	return a.tokenType
+}
+
+// Name returns a description of the credentials for logging
+func (a *TokenAuth) Name() string {
+	return "token (" + string(a.tokenType) + ")"
+}
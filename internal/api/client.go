@@ -1,12 +1,15 @@
 package api
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/time/rate"
@@ -17,6 +20,30 @@ const (
 	DefaultBaseURL = "https://hub.docker.com/v2"
 	// DefaultPageSize is the default page size for API requests
 	DefaultPageSize = 100
+	// MaxPageSize is the largest page size Docker Hub accepts
+	MaxPageSize = 100
+	// DefaultRateInterval is the sustained interval between API requests
+	DefaultRateInterval = time.Second
+	// DefaultRateBurst is the number of API requests allowed in a burst
+	DefaultRateBurst = 5
+	// DefaultMaxRetries is the number of retries for a failed page fetch
+	DefaultMaxRetries = 3
+	// DefaultRegistryURL is the Docker Hub registry (distribution API) base URL
+	DefaultRegistryURL = "https://registry-1.docker.io"
+	// DefaultRegistryAuthURL issues tokens for the registry
+	DefaultRegistryAuthURL = "https://auth.docker.io/token"
+)
+
+// TokenType identifies the kind of access token used for authentication
+type TokenType string
+
+const (
+	// TokenTypeJWT is a session JWT as returned by the login endpoint
+	TokenTypeJWT TokenType = "jwt"
+	// TokenTypePAT is a personal access token
+	TokenTypePAT TokenType = "pat"
+	// TokenTypeOAT is an organization access token
+	TokenTypeOAT TokenType = "oat"
 )
 
 // Client represents a Docker Hub API client
@@ -24,59 +51,110 @@ type Client struct {
 	baseURL    string
 	httpClient *http.Client
 	token      string
+	authScheme string
 	username   string
+	password   string // kept for registry token requests (password login only)
+	registry   string
+	authURL    string
+	tokens     registryTokens
+	pageSize   int
+	maxRetries int
 	limiter    *rate.Limiter
+	stats      stats
+	logger     *slog.Logger
+	sleep      func(ctx context.Context, d time.Duration) error // backoff sleeper (replaceable in tests)
+	throttled  atomic.Int64                                     // number of 429 responses received
 }
 
-// NewClient creates a new Docker Hub API client
-func NewClient() *Client {
-	return &Client{
-		baseURL: DefaultBaseURL,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		limiter: rate.NewLimiter(rate.Every(time.Second), 5), // 5 requests per second
+// Option configures a Client
+type Option func(*Client)
+
+// WithPageSize sets the page size used when listing tags
+// Values outside 1..MaxPageSize are ignored
+func WithPageSize(n int) Option {
+	return func(c *Client) {
+		if n >= 1 && n <= MaxPageSize {
+			c.pageSize = n
+		}
 	}
 }
 
-// Authenticate authenticates with Docker Hub using username and password
-func (c *Client) Authenticate(ctx context.Context, username, password string) error {
-	loginReq := LoginRequest{
-		Username: username,
-		Password: password,
+// WithMaxRetries sets how often a page fetch is retried after a transient
+// failure (network error or 5xx response); 0 disables retries
+func WithMaxRetries(n int) Option {
+	return func(c *Client) {
+		if n >= 0 {
+			c.maxRetries = n
+		}
 	}
+}
 
-	body, err := json.Marshal(loginReq)
-	if err != nil {
-		return fmt.Errorf("failed to marshal login request: %w", err)
+// WithHTTPClient replaces the internally-constructed HTTP client
+// The caller's client timeout and transport take over; rate limiting still applies
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient != nil {
+			c.httpClient = httpClient
+		}
 	}
+}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/users/login/", bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+// WithLogger sets the logger for client diagnostics (default: slog.Default())
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Client) {
+		if logger != nil {
+			c.logger = logger
+		}
 	}
+}
 
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.doRequest(req)
-	if err != nil {
-		return err
+// NewClient creates a new Docker Hub API client
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		baseURL: DefaultBaseURL,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		authScheme: "JWT",
+		// The limiter is shared by everything using this client, so it caps the
+		// total request rate regardless of how many workers issue requests
+		limiter:    rate.NewLimiter(rate.Every(DefaultRateInterval), DefaultRateBurst),
+		registry:   DefaultRegistryURL,
+		authURL:    DefaultRegistryAuthURL,
+		pageSize:   DefaultPageSize,
+		maxRetries: DefaultMaxRetries,
+		logger:     slog.Default(),
+		sleep:      sleepContext,
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return NewAPIError(resp.StatusCode, "/users/login/", string(bodyBytes))
+	for _, opt := range opts {
+		opt(c)
 	}
 
-	var loginResp LoginResponse
-	if err := json.NewDecoder(resp.Body).Decode(&loginResp); err != nil {
-		return fmt.Errorf("failed to decode login response: %w", err)
-	}
+	return c
+}
 
-	c.token = loginResp.Token
-	c.username = username
-	return nil
+// MaxUsefulConcurrency returns the number of concurrent workers the shared
+// rate limiter can serve without throttling; extra workers only wait on it
+func (c *Client) MaxUsefulConcurrency() int {
+	return c.limiter.Burst()
+}
+
+// Pace waits on the shared rate limiter as if a request were made,
+// without sending one (used to simulate real timing in dry-run)
+func (c *Client) Pace(ctx context.Context) error {
+	return c.limiter.Wait(ctx)
+}
+
+// Throttled returns the number of 429 responses received so far
+// Callers can compare successive values to detect new throttling
+func (c *Client) Throttled() int64 {
+	return c.throttled.Load()
+}
+
+// Authenticate authenticates with Docker Hub using username and password
+func (c *Client) Authenticate(ctx context.Context, username, password string) error {
+	return NewPasswordAuth(username, password).Authenticate(ctx, c)
 }
 
 // AuthenticateWithToken authenticates using a personal access token
@@ -84,41 +162,76 @@ func (c *Client) AuthenticateWithToken(token string) {
 	c.token = token
 }
 
+// AuthenticateWithTokenType authenticates using a token of the given type,
+// selecting the matching Authorization scheme (JWT or Bearer)
+func (c *Client) AuthenticateWithTokenType(token string, tokenType TokenType) error {
+	return NewTokenAuth(token, tokenType).Authenticate(context.Background(), c)
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// backoff sleeps before repeating a request and records the wait
+func (c *Client) backoff(ctx context.Context, d time.Duration) error {
+	c.record(ctx, func(s *Stats) { s.BackoffWait += d })
+	return c.sleep(ctx, d)
+}
+
 // doRequest performs an HTTP request with rate limiting and retries
 func (c *Client) doRequest(req *http.Request) (*http.Response, error) {
 	// Wait for rate limiter
+	start := time.Now()
 	if err := c.limiter.Wait(req.Context()); err != nil {
 		return nil, fmt.Errorf("rate limiter error: %w", err)
 	}
+	waited := time.Since(start)
+	c.record(req.Context(), func(s *Stats) { s.LimiterWait += waited })
 
-	// Add authorization header if token is available
-	if c.token != "" {
-		req.Header.Set("Authorization", "JWT "+c.token)
+	// Add authorization header if token is available (registry requests set their own)
+	if c.token != "" && req.Header.Get("Authorization") == "" {
+		req.Header.Set("Authorization", c.authScheme+" "+c.token)
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.send(req)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %s", ErrNetworkError, err)
+		return nil, fmt.Errorf("%w: %w", ErrNetworkError, err)
 	}
 
 	// Handle rate limiting with exponential backoff
 	if resp.StatusCode == http.StatusTooManyRequests {
 		resp.Body.Close()
+		c.throttle(req.Context())
 
 		// Exponential backoff: try up to 5 times
 		for i := 0; i < 5; i++ {
 			wait := time.Duration(1<<uint(i)) * time.Second // 1s, 2s, 4s, 8s, 16s
-			time.Sleep(wait)
+			if d, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				wait = d
+			}
+			if err := c.backoff(req.Context(), wait); err != nil {
+				return nil, err
+			}
 
-			resp, err = c.httpClient.Do(req)
+			resp, err = c.send(req)
 			if err != nil {
-				return nil, fmt.Errorf("%w: %s", ErrNetworkError, err)
+				return nil, fmt.Errorf("%w: %w", ErrNetworkError, err)
 			}
 
 			if resp.StatusCode != http.StatusTooManyRequests {
 				return resp, nil
 			}
 			resp.Body.Close()
+			c.throttle(req.Context())
 		}
 
 		return nil, ErrRateLimited
@@ -127,60 +240,170 @@ func (c *Client) doRequest(req *http.Request) (*http.Response, error) {
 	return resp, nil
 }
 
+// retryAfter parses a Retry-After header value, either delay seconds or an
+// HTTP date, into the wait from now
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
 // ListTags fetches all tags for a repository
 func (c *Client) ListTags(ctx context.Context, repo string) ([]Tag, error) {
+	return c.ListTagsLimited(ctx, repo, 0)
+}
+
+// ListTagsLimited fetches tags for a repository, stopping once limit tags
+// have been collected. A limit of 0 or less fetches all tags.
+// Tags are returned in Docker Hub's own order (roughly newest first).
+// A tag listed again on a later page (e.g., when tags were pushed during
+// pagination) is dropped: the first occurrence wins, metadata included.
+func (c *Client) ListTagsLimited(ctx context.Context, repo string, limit int) ([]Tag, error) {
 	var allTags []Tag
 	page := 1
+	guard := newPageGuard()
+	seen := make(map[string]bool)
 
 	for {
-		url := fmt.Sprintf("%s/repositories/%s/tags/?page=%d&page_size=%d", c.baseURL, repo, page, DefaultPageSize)
-
-		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %w", err)
-		}
-
-		resp, err := c.doRequest(req)
+		// Retry only the current page; pages already fetched are kept
+		var tagsResp *TagsResponse
+		err := c.retry(ctx, func() error {
+			var err error
+			tagsResp, err = c.fetchTagsPage(ctx, repo, page)
+			return err
+		})
 		if err != nil {
 			return nil, err
 		}
 
-		if resp.StatusCode == http.StatusNotFound {
-			resp.Body.Close()
-			return nil, ErrNotFound
-		}
-
-		if resp.StatusCode == http.StatusUnauthorized {
-			resp.Body.Close()
-			return nil, ErrUnauthorized
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			bodyBytes, _ := io.ReadAll(resp.Body)
-			resp.Body.Close()
-			return nil, NewAPIError(resp.StatusCode, url, string(bodyBytes))
+		for _, tag := range tagsResp.Results {
+			if seen[tag.Name] {
+				c.logger.Debug("Skipping duplicate tag in listing", "repository", repo, "tag", tag.Name, "page", page)
+				continue
+			}
+			seen[tag.Name] = true
+			allTags = append(allTags, tag)
 		}
 
-		var tagsResp TagsResponse
-		if err := json.NewDecoder(resp.Body).Decode(&tagsResp); err != nil {
-			resp.Body.Close()
-			return nil, fmt.Errorf("failed to decode tags response: %w", err)
+		// Stop early once the limit is reached
+		if limit > 0 && len(allTags) >= limit {
+			allTags = allTags[:limit]
+			break
 		}
-		resp.Body.Close()
-
-		allTags = append(allTags, tagsResp.Results...)
 
 		// Check if there are more pages
 		if tagsResp.Next == nil || *tagsResp.Next == "" {
 			break
 		}
 
+		if err := guard.check(*tagsResp.Next, tagsResp.Count, c.pageSize); err != nil {
+			return nil, fmt.Errorf("%w: listing tags for %s: %s", ErrInvalidResponse, repo, err)
+		}
+
 		page++
 	}
 
 	return allTags, nil
 }
 
+// fetchTagsPage fetches a single page of tags
+func (c *Client) fetchTagsPage(ctx context.Context, repo string, page int) (*TagsResponse, error) {
+	url := fmt.Sprintf("%s/repositories/%s/tags/?page=%d&page_size=%d", c.baseURL, repo, page, c.pageSize)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, ErrUnauthorized
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, NewAPIError(resp.StatusCode, url, string(bodyBytes))
+	}
+
+	var tagsResp TagsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tagsResp); err != nil {
+		return nil, fmt.Errorf("failed to decode tags response: %w", err)
+	}
+
+	return &tagsResp, nil
+}
+
+// retry calls fn until it succeeds, fails permanently, or maxRetries
+// retries are used up, with exponential backoff between attempts
+func (c *Client) retry(ctx context.Context, fn func() error) error {
+	err := fn()
+	for i := 0; i < c.maxRetries && isTransient(err); i++ {
+		wait := time.Duration(1<<uint(i)) * time.Second // 1s, 2s, 4s, ...
+		if err := c.backoff(ctx, wait); err != nil {
+			return err
+		}
+		c.record(ctx, func(s *Stats) { s.Retries++ })
+		err = fn()
+	}
+	return err
+}
+
+// isTransient reports whether err may succeed on retry
+// (network errors and server-side 5xx responses)
+func isTransient(err error) bool {
+	return errors.Is(err, ErrNetworkError)
+}
+
+// pageGuard detects paginated responses that never terminate
+type pageGuard struct {
+	seen  map[string]bool
+	pages int
+}
+
+// newPageGuard creates a new pagination guard
+func newPageGuard() *pageGuard {
+	return &pageGuard{seen: make(map[string]bool)}
+}
+
+// check records a fetched page and fails if next points to a page already
+// seen or if more pages were fetched than count results can fill
+func (g *pageGuard) check(next string, count, pageSize int) error {
+	g.pages++
+
+	if g.seen[next] {
+		return fmt.Errorf("next page %s was already fetched (after %d pages)", next, g.pages)
+	}
+	g.seen[next] = true
+
+	if count > 0 {
+		maxPages := (count+pageSize-1)/pageSize + 1 // allow for tags pushed mid-listing
+		if g.pages >= maxPages {
+			return fmt.Errorf("pagination exceeded %d pages for %d results", maxPages, count)
+		}
+	}
+
+	return nil
+}
+
 // DeleteTag deletes a specific tag from a repository
 func (c *Client) DeleteTag(ctx context.Context, repo, tag string) error {
 	url := fmt.Sprintf("%s/repositories/%s/tags/%s/", c.baseURL, repo, tag)
@@ -212,6 +435,42 @@ func (c *Client) DeleteTag(ctx context.Context, repo, tag string) error {
 	return nil
 }
 
+// GetTagImages fetches the images of a tag including their layers
+func (c *Client) GetTagImages(ctx context.Context, repo, tag string) ([]ImageDetail, error) {
+	url := fmt.Sprintf("%s/repositories/%s/tags/%s/images", c.baseURL, repo, tag)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, ErrUnauthorized
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, NewAPIError(resp.StatusCode, url, string(bodyBytes))
+	}
+
+	var images []ImageDetail
+	if err := json.NewDecoder(resp.Body).Decode(&images); err != nil {
+		return nil, fmt.Errorf("failed to decode images response: %w", err)
+	}
+
+	return images, nil
+}
+
 // GetRepository fetches repository information
 func (c *Client) GetRepository(ctx context.Context, repo string) (*Repository, error) {
 	url := fmt.Sprintf("%s/repositories/%s/", c.baseURL, repo)
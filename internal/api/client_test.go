@@ -0,0 +1,425 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// newTestClient returns a client for the test server with no rate limit and
+// a sleeper that records each backoff instead of waiting
+func newTestClient(t *testing.T, srv *httptest.Server, opts ...Option) (*Client, *[]time.Duration) {
+	t.Helper()
+	c := NewClient(opts...)
+	c.baseURL = srv.URL
+	c.limiter = rate.NewLimiter(rate.Inf, 1)
+
+	var mu sync.Mutex
+	var sleeps []time.Duration
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sleeps = append(sleeps, d)
+		return nil
+	}
+	return c, &sleeps
+}
+
+func TestDoRequestBacksOffOnRateLimit(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= 3 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c, sleeps := newTestClient(t, srv)
+	if err := c.DeleteTag(context.Background(), "org/repo", "v1"); err != nil {
+		t.Fatalf("DeleteTag() error = %v", err)
+	}
+
+	if want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}; !reflect.DeepEqual(*sleeps, want) {
+		t.Errorf("backoff = %v, want %v", *sleeps, want)
+	}
+	if calls != 4 {
+		t.Errorf("requests = %d, want 4", calls)
+	}
+	if got := c.Throttled(); got != 3 {
+		t.Errorf("Throttled() = %d, want 3", got)
+	}
+}
+
+func TestDoRequestGivesUpAfterFiveRetries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	c, sleeps := newTestClient(t, srv)
+	err := c.DeleteTag(context.Background(), "org/repo", "v1")
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("DeleteTag() error = %v, want %v", err, ErrRateLimited)
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second}
+	if !reflect.DeepEqual(*sleeps, want) {
+		t.Errorf("backoff = %v, want %v", *sleeps, want)
+	}
+}
+
+func TestDoRequestHonorsRetryAfter(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			// Unparseable values fall back to the exponential schedule
+			w.Header().Set("Retry-After", "soon")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	c, sleeps := newTestClient(t, srv)
+	if err := c.DeleteTag(context.Background(), "org/repo", "v1"); err != nil {
+		t.Fatalf("DeleteTag() error = %v", err)
+	}
+
+	if want := []time.Duration{7 * time.Second, 2 * time.Second}; !reflect.DeepEqual(*sleeps, want) {
+		t.Errorf("backoff = %v, want %v", *sleeps, want)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{"30", 30 * time.Second, true},
+		{"-1", 0, false},
+		{"Mon, 01 Jan 2024 00:00:45 GMT", 45 * time.Second, true},
+		{"Sun, 31 Dec 2023 23:59:00 GMT", 0, true}, // already passed
+		{"later", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// writeTagsPage writes a page of the tags endpoint
+func writeTagsPage(t *testing.T, w http.ResponseWriter, count int, next string, names ...string) {
+	t.Helper()
+	page := TagsResponse{Count: count}
+	if next != "" {
+		page.Next = &next
+	}
+	for _, name := range names {
+		page.Results = append(page.Results, Tag{Name: name})
+	}
+	writeJSON(t, w, page)
+}
+
+func TestListTagsStopsOnSelfReferentialNext(t *testing.T) {
+	var srv *httptest.Server
+	var calls int
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		// Every page claims the same next page
+		writeTagsPage(t, w, 0, srv.URL+"/repositories/org/repo/tags/?page=2", "v1")
+	}))
+	defer srv.Close()
+
+	c, _ := newTestClient(t, srv)
+	_, err := c.ListTags(context.Background(), "org/repo")
+	if !errors.Is(err, ErrInvalidResponse) {
+		t.Fatalf("ListTags() error = %v, want %v", err, ErrInvalidResponse)
+	}
+	if calls != 2 {
+		t.Errorf("requests = %d, want 2", calls)
+	}
+}
+
+func TestListTagsStopsAfterMaxPages(t *testing.T) {
+	var srv *httptest.Server
+	var calls int
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		// count says 4 tags (2 pages of 2), but next never runs out
+		next := fmt.Sprintf("%s/repositories/org/repo/tags/?page=%d", srv.URL, calls+1)
+		writeTagsPage(t, w, 4, next, fmt.Sprintf("a%d", calls), fmt.Sprintf("b%d", calls))
+	}))
+	defer srv.Close()
+
+	c, _ := newTestClient(t, srv, WithPageSize(2))
+	_, err := c.ListTags(context.Background(), "org/repo")
+	if !errors.Is(err, ErrInvalidResponse) {
+		t.Fatalf("ListTags() error = %v, want %v", err, ErrInvalidResponse)
+	}
+	// 2 pages for the count plus one for tags pushed mid-listing
+	if calls != 3 {
+		t.Errorf("requests = %d, want 3", calls)
+	}
+}
+
+// pagedTags serves pages of tag names, page 1 first; failures[n] requests to
+// page n fail with a 500 before it is served (-1 fails it every time)
+func pagedTags(t *testing.T, pages [][]string, failures map[int]int) (*httptest.Server, map[int]int) {
+	t.Helper()
+	var srv *httptest.Server
+	var mu sync.Mutex
+	requests := make(map[int]int)
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil || page < 1 || page > len(pages) {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		requests[page]++
+		n := requests[page]
+		mu.Unlock()
+		if f := failures[page]; f < 0 || n <= f {
+			http.Error(w, "upstream unavailable", http.StatusInternalServerError)
+			return
+		}
+
+		count := 0
+		for _, names := range pages {
+			count += len(names)
+		}
+		next := ""
+		if page < len(pages) {
+			next = fmt.Sprintf("%s/repositories/org/repo/tags/?page=%d", srv.URL, page+1)
+		}
+		writeTagsPage(t, w, count, next, pages[page-1]...)
+	}))
+	return srv, requests
+}
+
+func TestListTagsRetriesFailedPage(t *testing.T) {
+	pages := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}
+	srv, requests := pagedTags(t, pages, map[int]int{2: 2})
+	defer srv.Close()
+
+	c, sleeps := newTestClient(t, srv, WithPageSize(2), WithMaxRetries(3))
+	tags, err := c.ListTags(context.Background(), "org/repo")
+	if err != nil {
+		t.Fatalf("ListTags() error = %v", err)
+	}
+
+	if got, want := names(tags), []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tags = %v, want %v", got, want)
+	}
+	// Only the failed page is fetched again
+	if want := map[int]int{1: 1, 2: 3, 3: 1}; !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests per page = %v, want %v", requests, want)
+	}
+	if want := []time.Duration{time.Second, 2 * time.Second}; !reflect.DeepEqual(*sleeps, want) {
+		t.Errorf("backoff = %v, want %v", *sleeps, want)
+	}
+	if got := c.Stats().Retries; got != 2 {
+		t.Errorf("Stats().Retries = %d, want 2", got)
+	}
+}
+
+func TestListTagsSurfacesPermanentPageFailure(t *testing.T) {
+	pages := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}
+	srv, requests := pagedTags(t, pages, map[int]int{2: -1})
+	defer srv.Close()
+
+	c, _ := newTestClient(t, srv, WithPageSize(2), WithMaxRetries(2))
+	tags, err := c.ListTags(context.Background(), "org/repo")
+	if err == nil {
+		t.Fatalf("ListTags() = %v, want an error", names(tags))
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("ListTags() error = %v, want the 500 response", err)
+	}
+	if want := map[int]int{1: 1, 2: 3}; !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests per page = %v, want %v", requests, want)
+	}
+}
+
+func names(tags []Tag) []string {
+	out := make([]string, len(tags))
+	for i, tag := range tags {
+		out[i] = tag.Name
+	}
+	return out
+}
+
+func TestListTagsDropsTagsRepeatedAcrossPages(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// "b" moved to page 2 after a push while listing
+		switch r.URL.Query().Get("page") {
+		case "1":
+			writeJSON(t, w, TagsResponse{Count: 4, Next: ptr(srv.URL + "/repositories/org/repo/tags/?page=2"),
+				Results: []Tag{{Name: "a"}, {Name: "b", Digest: "sha256:first"}}})
+		default:
+			writeJSON(t, w, TagsResponse{Count: 4,
+				Results: []Tag{{Name: "b", Digest: "sha256:second"}, {Name: "c"}}})
+		}
+	}))
+	defer srv.Close()
+
+	c, _ := newTestClient(t, srv, WithPageSize(2))
+	tags, err := c.ListTags(context.Background(), "org/repo")
+	if err != nil {
+		t.Fatalf("ListTags() error = %v", err)
+	}
+
+	if got, want := names(tags), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tags = %v, want %v", got, want)
+	}
+	// The first occurrence wins, metadata included
+	if tags[1].Digest != "sha256:first" {
+		t.Errorf("b digest = %q, want the first occurrence", tags[1].Digest)
+	}
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v any) {
+	t.Helper()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Error(err)
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func TestWithStatsCountsPerContext(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c, _ := newTestClient(t, srv)
+	ctxA, statsA := WithStats(context.Background())
+	ctxB, statsB := WithStats(context.Background())
+
+	// a is throttled once, b is not
+	if err := c.DeleteTag(ctxA, "org/a", "v1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range []string{"v1", "v2"} {
+		if err := c.DeleteTag(ctxB, "org/b", tag); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a, b, total := statsA(), statsB(), c.Stats()
+	if a.Requests != 2 || a.Deletes != 2 || a.RateLimited != 1 || a.BackoffWait != time.Second {
+		t.Errorf("a = %+v, want 2 requests, 1 rate limited, 1s backoff", a)
+	}
+	if b.Requests != 2 || b.RateLimited != 0 || b.BackoffWait != 0 {
+		t.Errorf("b = %+v, want 2 requests and no throttling", b)
+	}
+	if total.Requests != 4 || total.RateLimited != 1 {
+		t.Errorf("client = %+v, want 4 requests, 1 rate limited", total)
+	}
+}
+
+func TestWithRequestTimeoutBoundsRoundTripOnly(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repositories/org/repo/tags/slow/" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c, _ := newTestClient(t, srv)
+	ctx := WithRequestTimeout(context.Background(), 50*time.Millisecond)
+
+	// Waiting for the rate limiter is not part of the round trip
+	c.limiter = rate.NewLimiter(rate.Every(150*time.Millisecond), 1)
+	c.limiter.Allow()
+	if err := c.DeleteTag(ctx, "org/repo", "fast"); err != nil {
+		t.Fatalf("DeleteTag(fast) error = %v", err)
+	}
+
+	c.limiter = rate.NewLimiter(rate.Inf, 1)
+	err := c.DeleteTag(ctx, "org/repo", "slow")
+	if !errors.Is(err, ErrRequestTimeout) {
+		t.Fatalf("DeleteTag(slow) error = %v, want %v", err, ErrRequestTimeout)
+	}
+}
+
+func TestListTagsDecodesLastPushed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"count": 3, "next": null, "results": [
+			{"name": "pushed", "last_updated": "2024-05-01T00:00:00Z", "tag_last_pushed": "2024-01-01T12:30:00.123456Z"},
+			{"name": "null", "last_updated": "2024-05-02T00:00:00Z", "tag_last_pushed": null},
+			{"name": "absent", "last_updated": "2024-05-03T00:00:00Z"}
+		]}`)
+	}))
+	defer srv.Close()
+
+	c, _ := newTestClient(t, srv)
+	tags, err := c.ListTags(context.Background(), "org/repo")
+	if err != nil {
+		t.Fatalf("ListTags() error = %v", err)
+	}
+	if len(tags) != 3 {
+		t.Fatalf("ListTags() returned %d tags, want 3", len(tags))
+	}
+
+	pushed := time.Date(2024, 1, 1, 12, 30, 0, 123456000, time.UTC)
+	for _, tt := range []struct {
+		tag        Tag
+		wantPushed time.Time
+		wantTime   time.Time // by last_pushed, falling back to last_updated
+	}{
+		{tags[0], pushed, pushed},
+		{tags[1], time.Time{}, time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)},
+		{tags[2], time.Time{}, time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC)},
+	} {
+		if !tt.tag.TagLastPushed.Equal(tt.wantPushed) {
+			t.Errorf("%s: TagLastPushed = %v, want %v", tt.tag.Name, tt.tag.TagLastPushed, tt.wantPushed)
+		}
+		if got := tt.tag.Time(AgeFieldLastPushed); !got.Equal(tt.wantTime) {
+			t.Errorf("%s: Time(last_pushed) = %v, want %v", tt.tag.Name, got, tt.wantTime)
+		}
+		if got := tt.tag.Time(AgeFieldLastUpdated); !got.Equal(tt.tag.LastUpdated) {
+			t.Errorf("%s: Time(last_updated) = %v, want %v", tt.tag.Name, got, tt.tag.LastUpdated)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"errors"
 	"fmt"
+	"net/http"
 )
 
 var (
@@ -14,8 +15,16 @@ var (
 	ErrRateLimited = errors.New("rate limit exceeded")
 	// ErrNetworkError indicates a network error occurred
 	ErrNetworkError = errors.New("network error")
+	// ErrRequestTimeout indicates an HTTP round trip exceeded the timeout set
+	// with WithRequestTimeout
+	ErrRequestTimeout = errors.New("request timed out")
 	// ErrInvalidResponse indicates invalid API response
 	ErrInvalidResponse = errors.New("invalid API response")
+	// ErrUnsupported indicates the registry backend lacks a feature
+	ErrUnsupported = errors.New("not supported by this registry")
+	// ErrSharedVersion indicates a tag cannot be deleted without the other tags
+	// of its manifest (GHCR package versions, OCI registries)
+	ErrSharedVersion = errors.New("tag shares its manifest with other tags")
 )
 
 // APIError represents an error from the Docker Hub API
@@ -30,6 +39,22 @@ func (e *APIError) Error() string {
 	return fmt.Sprintf("API error (status %d) at %s: %s", e.StatusCode, e.Endpoint, e.Message)
 }
 
+// Is maps the status code to the matching sentinel error, so that
+// errors.Is(err, ErrNotFound) holds for a 404 APIError
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrNetworkError:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
 // NewAPIError creates a new APIError
 func NewAPIError(statusCode int, endpoint, message string) *APIError {
 	return &APIError{
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ReadTags decodes tags from JSON, either an array of tags or a saved page
+// of the tags endpoint (an object with a "results" array)
+// The order is preserved, so sorters and policies see the tags exactly as
+// they are listed; this makes recorded responses usable as fixtures
+func ReadTags(r io.Reader) ([]Tag, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []Tag
+	if err := json.Unmarshal(data, &tags); err == nil {
+		return tags, nil
+	}
+
+	var page TagsResponse
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, fmt.Errorf("failed to decode tags: %w", err)
+	}
+	if page.Results == nil {
+		return nil, fmt.Errorf("failed to decode tags: expected an array of tags or an object with a \"results\" array")
+	}
+	return page.Results, nil
+}
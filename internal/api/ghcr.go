@@ -0,0 +1,407 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultGHCRBaseURL is the GitHub REST API base URL (ghcr.io images are GitHub packages)
+	DefaultGHCRBaseURL = "https://api.github.com"
+	// githubAPIVersion is the GitHub REST API version requested
+	githubAPIVersion = "2022-11-28"
+)
+
+// GHCRClient manages images on the GitHub Container Registry through the
+// GitHub packages API. A package version is one manifest with any number of
+// tags, and only whole versions can be deleted: a tag is deleted by deleting
+// its version, which is refused unless it is the version's only tag or all
+// of its tags are deleted together (see DeleteTags).
+type GHCRClient struct {
+	c *Client // transport: authorization, rate limiting, retries and stats
+
+	mu       sync.Mutex
+	owners   map[string]string                     // owner -> packages path ("orgs/<org>", "user" or "users/<user>")
+	versions map[string]map[string]*packageVersion // repo -> tag -> version, from the last listing
+}
+
+// packageVersion is a version of a GitHub container package
+type packageVersion struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"` // manifest digest
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Metadata  struct {
+		Container struct {
+			Tags []string `json:"tags"`
+		} `json:"container"`
+	} `json:"metadata"`
+}
+
+// githubPackage is a GitHub package as listed for its owner
+type githubPackage struct {
+	Name      string    `json:"name"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// githubAccount is a GitHub user or organization
+type githubAccount struct {
+	Login string `json:"login"`
+	Type  string `json:"type"` // "User" or "Organization"
+}
+
+// NewGHCRClient creates a new GHCR client
+func NewGHCRClient(opts ...Option) *GHCRClient {
+	c := NewClient(opts...)
+	c.baseURL = DefaultGHCRBaseURL
+	c.authScheme = "Bearer"
+
+	return &GHCRClient{
+		c:        c,
+		owners:   make(map[string]string),
+		versions: make(map[string]map[string]*packageVersion),
+	}
+}
+
+// Authenticate applies a token authenticator; GitHub takes the token as a
+// Bearer credential, whatever its declared type
+func (g *GHCRClient) Authenticate(ctx context.Context, auth Authenticator) error {
+	if _, ok := auth.(*TokenAuth); !ok {
+		return fmt.Errorf("GHCR requires a GitHub token (--token), not %s", auth.Name())
+	}
+	if err := auth.Authenticate(ctx, g.c); err != nil {
+		return err
+	}
+	g.c.authScheme = "Bearer"
+	return nil
+}
+
+// MaxUsefulConcurrency returns the number of concurrent workers the shared
+// rate limiter can serve without throttling
+func (g *GHCRClient) MaxUsefulConcurrency() int {
+	return g.c.MaxUsefulConcurrency()
+}
+
+// Pace waits on the shared rate limiter as if a request were made
+func (g *GHCRClient) Pace(ctx context.Context) error {
+	return g.c.Pace(ctx)
+}
+
+// Throttled returns the number of 429 responses received so far
+func (g *GHCRClient) Throttled() int64 {
+	return g.c.Throttled()
+}
+
+// Stats returns a snapshot of the client's API activity so far
+func (g *GHCRClient) Stats() Stats {
+	return g.c.Stats()
+}
+
+// ListTags fetches all tags of a container package
+func (g *GHCRClient) ListTags(ctx context.Context, repo string) ([]Tag, error) {
+	return g.ListTagsLimited(ctx, repo, 0)
+}
+
+// ListTagsLimited fetches the tags of a container package ("owner/name"),
+// stopping once limit tags have been collected (0 or less fetches all).
+// Tags of the same version share its digest and timestamps; untagged
+// versions are skipped.
+func (g *GHCRClient) ListTagsLimited(ctx context.Context, repo string, limit int) ([]Tag, error) {
+	base, err := g.packageURL(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []Tag
+	byTag := make(map[string]*packageVersion)
+
+	for page := 1; ; page++ {
+		// Retry only the current page; pages already fetched are kept
+		var versions []packageVersion
+		pageURL := fmt.Sprintf("%s/versions?per_page=%d&page=%d", base, g.c.pageSize, page)
+		if err := g.c.retry(ctx, func() error { return g.get(ctx, pageURL, &versions) }); err != nil {
+			return nil, err
+		}
+
+		for i := range versions {
+			v := &versions[i]
+			for _, name := range v.Metadata.Container.Tags {
+				if byTag[name] != nil {
+					continue
+				}
+				byTag[name] = v
+				tags = append(tags, Tag{
+					Name:          name,
+					LastUpdated:   v.UpdatedAt,
+					TagLastPushed: v.CreatedAt,
+					Digest:        v.Name,
+				})
+			}
+		}
+
+		// Stop early once the limit is reached
+		if limit > 0 && len(tags) >= limit {
+			tags = tags[:limit]
+			break
+		}
+
+		if len(versions) < g.c.pageSize {
+			break
+		}
+	}
+
+	g.mu.Lock()
+	g.versions[repo] = byTag
+	g.mu.Unlock()
+
+	return tags, nil
+}
+
+// DeleteTag deletes the package version of a tag, provided the version
+// carries no other tag (ErrSharedVersion otherwise)
+func (g *GHCRClient) DeleteTag(ctx context.Context, repo, tag string) error {
+	return g.DeleteTags(ctx, repo, []string{tag})
+}
+
+// DeleteTags deletes the package version the tags point to, provided they
+// are all of its tags (ErrSharedVersion otherwise)
+func (g *GHCRClient) DeleteTags(ctx context.Context, repo string, tags []string) error {
+	v, err := g.version(ctx, repo, tags[0])
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags[1:] {
+		other, err := g.version(ctx, repo, tag)
+		if err != nil {
+			return err
+		}
+		if other.ID != v.ID {
+			return fmt.Errorf("tags %s and %s belong to different package versions", tags[0], tag)
+		}
+	}
+
+	var others []string
+	for _, name := range v.Metadata.Container.Tags {
+		if !slices.Contains(tags, name) {
+			others = append(others, name)
+		}
+	}
+	if len(others) > 0 {
+		return fmt.Errorf("%w: %s is also tagged %s, and GHCR only deletes whole versions",
+			ErrSharedVersion, strings.Join(tags, ", "), strings.Join(others, ", "))
+	}
+
+	base, err := g.packageURL(ctx, repo)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", fmt.Sprintf("%s/versions/%d", base, v.ID), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if err := g.do(req, nil); err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	for _, tag := range tags {
+		delete(g.versions[repo], tag)
+	}
+	g.mu.Unlock()
+
+	return nil
+}
+
+// version returns the package version a tag points to, listing the
+// package again if the tag is not known from the last listing
+func (g *GHCRClient) version(ctx context.Context, repo, tag string) (*packageVersion, error) {
+	g.mu.Lock()
+	v := g.versions[repo][tag]
+	g.mu.Unlock()
+	if v != nil {
+		return v, nil
+	}
+
+	if _, err := g.ListTags(ctx, repo); err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	v = g.versions[repo][tag]
+	g.mu.Unlock()
+	if v == nil {
+		return nil, ErrNotFound
+	}
+	return v, nil
+}
+
+// ListRepositories fetches all container packages of an owner
+func (g *GHCRClient) ListRepositories(ctx context.Context, namespace string) ([]Repository, error) {
+	path, err := g.ownerPath(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []Repository
+	for page := 1; ; page++ {
+		var packages []githubPackage
+		pageURL := fmt.Sprintf("%s/%s/packages?package_type=container&per_page=%d&page=%d", g.c.baseURL, path, g.c.pageSize, page)
+		if err := g.c.retry(ctx, func() error { return g.get(ctx, pageURL, &packages) }); err != nil {
+			return nil, err
+		}
+
+		for _, p := range packages {
+			all = append(all, Repository{
+				Namespace:   namespace,
+				Name:        p.Name,
+				LastUpdated: p.UpdatedAt,
+			})
+		}
+
+		if len(packages) < g.c.pageSize {
+			break
+		}
+	}
+
+	return all, nil
+}
+
+// DeleteRepository deletes a container package with all its versions
+// This cannot be undone
+func (g *GHCRClient) DeleteRepository(ctx context.Context, repo string) error {
+	base, err := g.packageURL(ctx, repo)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", base, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	return g.do(req, nil)
+}
+
+// GetTagImages is not supported: the packages API does not report layers
+func (g *GHCRClient) GetTagImages(ctx context.Context, repo, tag string) ([]ImageDetail, error) {
+	return nil, fmt.Errorf("image layers: %w", ErrUnsupported)
+}
+
+// GetImageLabels is not supported: the packages API does not report labels
+func (g *GHCRClient) GetImageLabels(ctx context.Context, repo, tag string) (map[string]string, error) {
+	return nil, fmt.Errorf("image labels: %w", ErrUnsupported)
+}
+
+// ListUntagged is not supported: untagged GHCR versions include the
+// platform manifests of multi-platform images, which must not be pruned
+func (g *GHCRClient) ListUntagged(ctx context.Context, repo string) ([]Manifest, error) {
+	return nil, fmt.Errorf("untagged manifests: %w", ErrUnsupported)
+}
+
+// DeleteUntagged is not supported (see ListUntagged)
+func (g *GHCRClient) DeleteUntagged(ctx context.Context, repo string, digests []string) error {
+	return fmt.Errorf("untagged manifests: %w", ErrUnsupported)
+}
+
+// packageURL returns the API URL of a container package ("owner/name";
+// the name may contain further slashes)
+func (g *GHCRClient) packageURL(ctx context.Context, repo string) (string, error) {
+	owner, name, err := splitRepo(repo)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := g.ownerPath(ctx, owner)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/%s/packages/container/%s", g.c.baseURL, path, url.PathEscape(name)), nil
+}
+
+// ownerPath returns the API path of an owner's packages: "orgs/<org>" for
+// an organization, "user" for the token's own account and "users/<user>"
+// for any other user (readable, but only its owner may delete)
+func (g *GHCRClient) ownerPath(ctx context.Context, owner string) (string, error) {
+	g.mu.Lock()
+	path, ok := g.owners[owner]
+	g.mu.Unlock()
+	if ok {
+		return path, nil
+	}
+
+	var account githubAccount
+	if err := g.get(ctx, fmt.Sprintf("%s/users/%s", g.c.baseURL, url.PathEscape(owner)), &account); err != nil {
+		return "", fmt.Errorf("failed to look up package owner %s: %w", owner, err)
+	}
+
+	if account.Type == "Organization" {
+		path = "orgs/" + url.PathEscape(owner)
+	} else {
+		var self githubAccount
+		if err := g.get(ctx, g.c.baseURL+"/user", &self); err != nil {
+			return "", fmt.Errorf("failed to look up the token's account: %w", err)
+		}
+		path = "users/" + url.PathEscape(owner)
+		if strings.EqualFold(self.Login, owner) {
+			path = "user"
+		}
+	}
+
+	g.mu.Lock()
+	g.owners[owner] = path
+	g.mu.Unlock()
+
+	return path, nil
+}
+
+// get fetches a GitHub API URL and decodes the JSON response into v
+func (g *GHCRClient) get(ctx context.Context, apiURL string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	return g.do(req, v)
+}
+
+// do sends a GitHub API request and decodes the JSON response into v
+// (the body is discarded if v is nil)
+func (g *GHCRClient) do(req *http.Request, v any) error {
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", githubAPIVersion)
+
+	resp, err := g.c.doRequest(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return ErrUnauthorized
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return NewAPIError(resp.StatusCode, req.URL.String(), string(bodyBytes))
+	}
+
+	if v == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
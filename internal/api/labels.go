@@ -0,0 +1,212 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Manifest media types accepted from the registry
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// registryManifest is an image manifest or an index of per-platform manifests
+type registryManifest struct {
+	Config struct {
+		Digest string `json:"digest"`
+		Size   int64  `json:"size"`
+	} `json:"config"`
+	Layers    []Layer `json:"layers"`
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
+// imageConfig is the part of an image config blob holding the labels
+// (and the build time and platform, read by OCIClient)
+type imageConfig struct {
+	Created      time.Time `json:"created"`
+	OS           string    `json:"os"`
+	Architecture string    `json:"architecture"`
+	Config       struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"config"`
+}
+
+// registryTokens caches pull tokens for the registry per repository
+type registryTokens struct {
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+// GetImageLabels fetches the OCI labels of the image a tag points to.
+// Labels are not part of the Docker Hub API, so this reads the manifest and
+// config blob from the registry: two or three registry requests per tag.
+// For multi-platform tags the first real platform's labels are returned.
+func (c *Client) GetImageLabels(ctx context.Context, repo, tag string) (map[string]string, error) {
+	manifest, err := c.getManifest(ctx, repo, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve an index to a platform manifest, skipping attestations
+	if manifest.Config.Digest == "" {
+		ref := ""
+		for _, m := range manifest.Manifests {
+			if m.Platform.OS != "unknown" {
+				ref = m.Digest
+				break
+			}
+		}
+		if ref == "" {
+			return nil, fmt.Errorf("%w: no image manifest for tag %s", ErrInvalidResponse, tag)
+		}
+		manifest, err = c.getManifest(ctx, repo, ref)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := c.registryGet(ctx, repo, "/blobs/"+manifest.Config.Digest, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var config imageConfig
+	if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to decode image config: %w", err)
+	}
+
+	if config.Config.Labels == nil {
+		return map[string]string{}, nil
+	}
+	return config.Config.Labels, nil
+}
+
+// getManifest fetches a manifest by tag or digest
+func (c *Client) getManifest(ctx context.Context, repo, ref string) (*registryManifest, error) {
+	resp, err := c.registryGet(ctx, repo, "/manifests/"+ref, strings.Join(manifestMediaTypes, ", "))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var manifest registryManifest
+	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// registryGet performs an authenticated GET against the registry API,
+// refreshing the pull token once if it was rejected
+func (c *Client) registryGet(ctx context.Context, repo, path, accept string) (*http.Response, error) {
+	endpoint := fmt.Sprintf("%s/v2/%s%s", c.registry, repo, path)
+
+	for attempt := 0; ; attempt++ {
+		token, err := c.registryToken(ctx, repo, attempt > 0)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+
+		resp, err := c.doRequest(req)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return resp, nil
+		case resp.StatusCode == http.StatusUnauthorized && attempt == 0:
+			resp.Body.Close()
+			continue
+		case resp.StatusCode == http.StatusUnauthorized:
+			resp.Body.Close()
+			return nil, ErrUnauthorized
+		case resp.StatusCode == http.StatusNotFound:
+			resp.Body.Close()
+			return nil, ErrNotFound
+		default:
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, NewAPIError(resp.StatusCode, endpoint, string(bodyBytes))
+		}
+	}
+}
+
+// registryToken returns a pull token for repo, requesting a new one if none
+// is cached or refresh is set. Password credentials are used when available,
+// otherwise the token is anonymous (public repositories only).
+func (c *Client) registryToken(ctx context.Context, repo string, refresh bool) (string, error) {
+	c.tokens.mu.Lock()
+	defer c.tokens.mu.Unlock()
+
+	if token, ok := c.tokens.tokens[repo]; ok && !refresh {
+		return token, nil
+	}
+
+	query := url.Values{}
+	query.Set("service", "registry.docker.io")
+	query.Set("scope", "repository:"+repo+":pull")
+	endpoint := c.authURL + "?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.username != "" && c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrNetworkError, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return "", ErrUnauthorized
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return "", NewAPIError(resp.StatusCode, c.authURL, string(bodyBytes))
+	}
+
+	var tokenResp struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", fmt.Errorf("failed to decode registry token response: %w", err)
+	}
+
+	if c.tokens.tokens == nil {
+		c.tokens.tokens = make(map[string]string)
+	}
+	c.tokens.tokens[repo] = tokenResp.Token
+	return tokenResp.Token, nil
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// The manifest endpoints below belong to Docker Hub's image management API,
+// which is not part of the documented public API. They may be unavailable
+// for some accounts or change without notice.
+
+// splitRepo splits "namespace/name" into its parts
+func splitRepo(repo string) (namespace, name string, err error) {
+	namespace, name, ok := strings.Cut(repo, "/")
+	if !ok || namespace == "" || name == "" {
+		return "", "", fmt.Errorf("invalid repository %q (format: namespace/repo)", repo)
+	}
+	return namespace, name, nil
+}
+
+// ListUntagged fetches manifests in a repository that no tag points to
+func (c *Client) ListUntagged(ctx context.Context, repo string) ([]Manifest, error) {
+	namespace, name, err := splitRepo(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []Manifest
+	page := 1
+	guard := newPageGuard()
+
+	for {
+		url := fmt.Sprintf("%s/namespaces/%s/repositories/%s/images?currently_tagged=false&page=%d&page_size=%d",
+			c.baseURL, namespace, name, page, c.pageSize)
+
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := c.doRequest(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			return nil, ErrNotFound
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized {
+			resp.Body.Close()
+			return nil, ErrUnauthorized
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, NewAPIError(resp.StatusCode, url, string(bodyBytes))
+		}
+
+		var manifestsResp ManifestsResponse
+		if err := json.NewDecoder(resp.Body).Decode(&manifestsResp); err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to decode images response: %w", err)
+		}
+		resp.Body.Close()
+
+		all = append(all, manifestsResp.Results...)
+
+		if manifestsResp.Next == nil || *manifestsResp.Next == "" {
+			break
+		}
+
+		if err := guard.check(*manifestsResp.Next, manifestsResp.Count, c.pageSize); err != nil {
+			return nil, fmt.Errorf("%w: listing images for %s: %s", ErrInvalidResponse, repo, err)
+		}
+
+		page++
+	}
+
+	return all, nil
+}
+
+// DeleteUntagged deletes the given untagged manifests (as listed by
+// ListUntagged) in one request
+func (c *Client) DeleteUntagged(ctx context.Context, repo string, digests []string) error {
+	if len(digests) == 0 {
+		return nil
+	}
+
+	namespace, name, err := splitRepo(repo)
+	if err != nil {
+		return err
+	}
+
+	deleteReq := DeleteImagesRequest{}
+	for _, d := range digests {
+		deleteReq.Manifests = append(deleteReq.Manifests, ManifestRef{Repository: name, Digest: d})
+	}
+
+	body, err := json.Marshal(deleteReq)
+	if err != nil {
+		return fmt.Errorf("failed to marshal delete request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/namespaces/%s/delete-images", c.baseURL, namespace)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return ErrUnauthorized
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return NewAPIError(resp.StatusCode, url, string(bodyBytes))
+	}
+
+	return nil
+}
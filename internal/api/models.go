@@ -4,10 +4,34 @@ import "time"
 
 // Tag represents a Docker Hub image tag
 type Tag struct {
-	Name        string    `json:"name"`
-	LastUpdated time.Time `json:"last_updated"`
-	FullSize    int64     `json:"full_size"`
-	Images      []Image   `json:"images"`
+	Name          string            `json:"name"`
+	LastUpdated   time.Time         `json:"last_updated"`
+	TagLastPushed time.Time         `json:"tag_last_pushed"`
+	TagLastPulled time.Time         `json:"tag_last_pulled"` // zero if never pulled or not reported
+	Digest        string            `json:"digest"`
+	TagStatus     string            `json:"tag_status"`
+	FullSize      int64             `json:"full_size"`
+	Images        []Image           `json:"images"`
+	Labels        map[string]string `json:"-"` // OCI labels, only set when fetched via GetImageLabels
+}
+
+// AgeField selects which tag timestamp determines its age
+type AgeField string
+
+const (
+	// AgeFieldLastUpdated uses last_updated (may reflect metadata edits)
+	AgeFieldLastUpdated AgeField = "last_updated"
+	// AgeFieldLastPushed uses tag_last_pushed (the actual image push)
+	AgeFieldLastPushed AgeField = "last_pushed"
+)
+
+// Time returns the tag timestamp for the given age field
+// Falls back to LastUpdated when TagLastPushed is not set
+func (t Tag) Time(field AgeField) time.Time {
+	if field == AgeFieldLastPushed && !t.TagLastPushed.IsZero() {
+		return t.TagLastPushed
+	}
+	return t.LastUpdated
 }
 
 // Image represents individual image layers in a tag
@@ -15,6 +39,22 @@ type Image struct {
 	Architecture string `json:"architecture"`
 	OS           string `json:"os"`
 	Size         int64  `json:"size"`
+	Digest       string `json:"digest"`
+}
+
+// ImageDetail represents an image of a tag including its layers
+type ImageDetail struct {
+	Architecture string  `json:"architecture"`
+	OS           string  `json:"os"`
+	Digest       string  `json:"digest"`
+	Size         int64   `json:"size"`
+	Layers       []Layer `json:"layers"`
+}
+
+// Layer represents a single image layer
+type Layer struct {
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
 }
 
 // LoginRequest represents the Docker Hub login request
@@ -38,8 +78,54 @@ type TagsResponse struct {
 
 // Repository represents a Docker Hub repository
 type Repository struct {
-	User        string `json:"user"`
-	Name        string `json:"name"`
-	Namespace   string `json:"namespace"`
-	Description string `json:"description"`
+	User        string      `json:"user"`
+	Name        string      `json:"name"`
+	Namespace   string      `json:"namespace"`
+	Description string      `json:"description"`
+	Permissions Permissions `json:"permissions"`
+	PullCount   int64       `json:"pull_count"`
+	StarCount   int         `json:"star_count"`
+	LastUpdated time.Time   `json:"last_updated"`
+}
+
+// RepositoriesResponse represents the paginated repositories response from Docker Hub
+type RepositoriesResponse struct {
+	Count    int          `json:"count"`
+	Next     *string      `json:"next"`
+	Previous *string      `json:"previous"`
+	Results  []Repository `json:"results"`
+}
+
+// Permissions represents the caller's permissions on a repository
+type Permissions struct {
+	Read  bool `json:"read"`
+	Write bool `json:"write"`
+	Admin bool `json:"admin"`
+}
+
+// Manifest represents an image manifest in a repository
+type Manifest struct {
+	Digest     string    `json:"digest"`
+	LastPushed time.Time `json:"last_pushed"`
+	Status     string    `json:"status"`
+}
+
+// ManifestsResponse represents the paginated images response from Docker Hub
+type ManifestsResponse struct {
+	Count    int        `json:"count"`
+	Next     *string    `json:"next"`
+	Previous *string    `json:"previous"`
+	Results  []Manifest `json:"results"`
+}
+
+// ManifestRef identifies a manifest to delete
+type ManifestRef struct {
+	Repository string `json:"repository"`
+	Digest     string `json:"digest"`
+}
+
+// DeleteImagesRequest represents the Docker Hub bulk image deletion request
+type DeleteImagesRequest struct {
+	DryRun    bool          `json:"dry_run"`
+	Manifests []ManifestRef `json:"manifests"`
 }
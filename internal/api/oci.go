@@ -0,0 +1,576 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DefaultOCIRateInterval is the sustained interval between requests to a
+	// self-hosted registry (which has no published rate limit)
+	DefaultOCIRateInterval = 20 * time.Millisecond
+	// DefaultOCIRateBurst is the number of registry requests allowed in a burst
+	DefaultOCIRateBurst = 10
+)
+
+// challengeParam matches a key="value" parameter of a WWW-Authenticate header
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// OCIClient manages images on a registry speaking the OCI distribution API
+// (registry:2, Nexus, Artifactory, Harbor, ...). The API keeps no tag
+// metadata, so listing reads each tag's manifest and image config (two or
+// three requests per tag). Tags are deleted by deleting their manifest,
+// which is refused while another tag points at it.
+type OCIClient struct {
+	c *Client // transport: rate limiting, retries and stats
+
+	username string
+	password string
+	token    string // static Bearer token, instead of username and password
+
+	mu      sync.Mutex
+	auth    map[string]string            // repo -> Authorization header answering the last challenge
+	digests map[string]map[string]string // repo -> tag -> manifest digest, from the last listing
+}
+
+// tagNames is a page of the tags list or catalog endpoint
+type tagNames struct {
+	Tags         []string `json:"tags"`
+	Repositories []string `json:"repositories"`
+}
+
+// NewOCIClient creates a new client for the registry at registryURL
+// (e.g. https://registry.example.com)
+func NewOCIClient(registryURL string, opts ...Option) *OCIClient {
+	c := NewClient(opts...)
+	c.registry = strings.TrimSuffix(registryURL, "/")
+	c.limiter = rate.NewLimiter(rate.Every(DefaultOCIRateInterval), DefaultOCIRateBurst)
+
+	return &OCIClient{
+		c:       c,
+		auth:    make(map[string]string),
+		digests: make(map[string]map[string]string),
+	}
+}
+
+// Authenticate takes the credentials of a password or token authenticator
+// (nil for anonymous access) and checks them against the registry
+func (o *OCIClient) Authenticate(ctx context.Context, auth Authenticator) error {
+	switch a := auth.(type) {
+	case nil:
+	case *PasswordAuth:
+		o.username, o.password = a.username, a.password
+	case *TokenAuth:
+		o.token = a.token
+	default:
+		return fmt.Errorf("unsupported credentials for an OCI registry: %s", auth.Name())
+	}
+
+	resp, err := o.request(ctx, "GET", "", o.c.registry+"/v2/", "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// MaxUsefulConcurrency returns the number of concurrent workers the shared
+// rate limiter can serve without throttling
+func (o *OCIClient) MaxUsefulConcurrency() int {
+	return o.c.MaxUsefulConcurrency()
+}
+
+// Pace waits on the shared rate limiter as if a request were made
+func (o *OCIClient) Pace(ctx context.Context) error {
+	return o.c.Pace(ctx)
+}
+
+// Throttled returns the number of 429 responses received so far
+func (o *OCIClient) Throttled() int64 {
+	return o.c.Throttled()
+}
+
+// Stats returns a snapshot of the client's API activity so far
+func (o *OCIClient) Stats() Stats {
+	return o.c.Stats()
+}
+
+// ListTags fetches all tags of a repository
+func (o *OCIClient) ListTags(ctx context.Context, repo string) ([]Tag, error) {
+	return o.ListTagsLimited(ctx, repo, 0)
+}
+
+// ListTagsLimited fetches the tags of a repository in the registry's order
+// (usually lexical), stopping once limit tags have been collected (0 or
+// less fetches all). A tag's time is its image's build time ("created" in
+// the image config); tags without one have a zero time.
+func (o *OCIClient) ListTagsLimited(ctx context.Context, repo string, limit int) ([]Tag, error) {
+	names, err := o.listNames(ctx, repo, fmt.Sprintf("%s/v2/%s/tags/list?n=%d", o.c.registry, repo, o.c.pageSize), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]Tag, 0, len(names))
+	digests := make(map[string]string, len(names))
+	for _, name := range names {
+		tag, _, err := o.describe(ctx, repo, name)
+		if errors.Is(err, ErrNotFound) {
+			o.c.logger.Debug("Skipping tag deleted during listing", "repository", repo, "tag", name)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tag %s: %w", name, err)
+		}
+		digests[name] = tag.Digest
+		tags = append(tags, tag)
+	}
+
+	o.mu.Lock()
+	o.digests[repo] = digests
+	o.mu.Unlock()
+
+	return tags, nil
+}
+
+// listNames fetches the names of a paginated tags list or catalog,
+// following Link headers, until limit names are collected (0 = all)
+func (o *OCIClient) listNames(ctx context.Context, repo, endpoint string, limit int) ([]string, error) {
+	var names []string
+	guard := newPageGuard()
+
+	for {
+		// Retry only the current page; pages already fetched are kept
+		var page tagNames
+		var next string
+		err := o.c.retry(ctx, func() error {
+			resp, err := o.request(ctx, "GET", repo, endpoint, "")
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+
+			page = tagNames{}
+			if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+				return fmt.Errorf("failed to decode %s: %w", endpoint, err)
+			}
+			next, err = nextPage(endpoint, resp.Header.Get("Link"))
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		names = append(names, page.Tags...)
+		names = append(names, page.Repositories...)
+
+		if limit > 0 && len(names) >= limit {
+			return names[:limit], nil
+		}
+
+		if next == "" {
+			return names, nil
+		}
+
+		if err := guard.check(next, 0, o.c.pageSize); err != nil {
+			return nil, fmt.Errorf("%w: listing %s: %s", ErrInvalidResponse, endpoint, err)
+		}
+		endpoint = next
+	}
+}
+
+// nextPage returns the absolute URL of the rel="next" entry of a Link
+// header ("" if there is none)
+func nextPage(endpoint, link string) (string, error) {
+	for _, entry := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(entry, ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+
+		base, err := url.Parse(endpoint)
+		if err != nil {
+			return "", err
+		}
+		ref, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+		if err != nil {
+			return "", fmt.Errorf("%w: bad Link header %q", ErrInvalidResponse, link)
+		}
+		return base.ResolveReference(ref).String(), nil
+	}
+	return "", nil
+}
+
+// describe reads a tag's manifest and image config into a Tag
+// For multi-platform tags, the size, time and config are the first real
+// platform's; the config is nil for artifacts that are not images
+func (o *OCIClient) describe(ctx context.Context, repo, name string) (Tag, *imageConfig, error) {
+	manifest, digest, err := o.manifest(ctx, repo, name)
+	if err != nil {
+		return Tag{}, nil, err
+	}
+	tag := Tag{Name: name, Digest: digest}
+
+	// Resolve an index to a platform manifest, skipping attestations
+	if manifest.Config.Digest == "" {
+		for _, m := range manifest.Manifests {
+			if m.Platform.OS == "unknown" {
+				continue
+			}
+			tag.Images = append(tag.Images, Image{
+				Architecture: m.Platform.Architecture,
+				OS:           m.Platform.OS,
+				Digest:       m.Digest,
+			})
+		}
+		if len(tag.Images) == 0 {
+			return tag, nil, nil
+		}
+		manifest, _, err = o.manifest(ctx, repo, tag.Images[0].Digest)
+		if err != nil {
+			return Tag{}, nil, err
+		}
+	}
+
+	tag.FullSize = manifest.Config.Size
+	for _, layer := range manifest.Layers {
+		tag.FullSize += layer.Size
+	}
+
+	resp, err := o.request(ctx, "GET", repo, fmt.Sprintf("%s/v2/%s/blobs/%s", o.c.registry, repo, manifest.Config.Digest), "")
+	if err != nil {
+		return Tag{}, nil, err
+	}
+	defer resp.Body.Close()
+
+	var config imageConfig
+	if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return Tag{}, nil, fmt.Errorf("failed to decode image config: %w", err)
+	}
+
+	tag.LastUpdated = config.Created
+	tag.TagLastPushed = config.Created
+	if len(tag.Images) == 0 {
+		tag.Images = []Image{{Architecture: config.Architecture, OS: config.OS, Digest: digest}}
+	}
+	tag.Images[0].Size = tag.FullSize
+
+	return tag, &config, nil
+}
+
+// manifest fetches a manifest by tag or digest and returns it with its digest
+func (o *OCIClient) manifest(ctx context.Context, repo, ref string) (*registryManifest, string, error) {
+	resp, err := o.request(ctx, "GET", repo, fmt.Sprintf("%s/v2/%s/manifests/%s", o.c.registry, repo, ref), strings.Join(manifestMediaTypes, ", "))
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %s", ErrNetworkError, err)
+	}
+
+	// Registries should send the digest; it is the hash of the manifest bytes
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		digest = fmt.Sprintf("sha256:%x", sha256.Sum256(body))
+	}
+
+	var manifest registryManifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, "", fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	return &manifest, digest, nil
+}
+
+// DeleteTag deletes the manifest a tag points to, provided no other tag
+// points at it (ErrSharedVersion otherwise)
+func (o *OCIClient) DeleteTag(ctx context.Context, repo, tag string) error {
+	return o.DeleteTags(ctx, repo, []string{tag})
+}
+
+// DeleteTags deletes the manifest the tags point to, provided they are all
+// of its tags (ErrSharedVersion otherwise). Other tags are known from the
+// last full listing, so tags must have been listed without a limit.
+func (o *OCIClient) DeleteTags(ctx context.Context, repo string, tags []string) error {
+	digest, others, err := o.digest(ctx, repo, tags[0])
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags[1:] {
+		d, _, err := o.digest(ctx, repo, tag)
+		if err != nil {
+			return err
+		}
+		if d != digest {
+			return fmt.Errorf("tags %s and %s point to different manifests", tags[0], tag)
+		}
+	}
+
+	var remaining []string
+	for _, name := range others {
+		if !slices.Contains(tags, name) {
+			remaining = append(remaining, name)
+		}
+	}
+	if len(remaining) > 0 {
+		sort.Strings(remaining)
+		return fmt.Errorf("%w: %s is also tagged %s, and the registry only deletes whole manifests",
+			ErrSharedVersion, strings.Join(tags, ", "), strings.Join(remaining, ", "))
+	}
+
+	resp, err := o.request(ctx, "DELETE", repo, fmt.Sprintf("%s/v2/%s/manifests/%s", o.c.registry, repo, digest), "")
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusMethodNotAllowed {
+		return fmt.Errorf("registry does not allow deletes (registry:2 needs REGISTRY_STORAGE_DELETE_ENABLED=true): %w", err)
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	o.mu.Lock()
+	for _, tag := range tags {
+		delete(o.digests[repo], tag)
+	}
+	o.mu.Unlock()
+
+	return nil
+}
+
+// digest returns the manifest digest of a tag and the other tags pointing
+// at it, listing the repository again if the tag is not known
+func (o *OCIClient) digest(ctx context.Context, repo, tag string) (string, []string, error) {
+	o.mu.Lock()
+	_, known := o.digests[repo][tag]
+	o.mu.Unlock()
+
+	if !known {
+		if _, err := o.ListTags(ctx, repo); err != nil {
+			return "", nil, err
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	digest, ok := o.digests[repo][tag]
+	if !ok {
+		return "", nil, ErrNotFound
+	}
+
+	var others []string
+	for name, d := range o.digests[repo] {
+		if d == digest && name != tag {
+			others = append(others, name)
+		}
+	}
+	return digest, others, nil
+}
+
+// GetImageLabels fetches the OCI labels of the image a tag points to
+// For multi-platform tags the first real platform's labels are returned
+func (o *OCIClient) GetImageLabels(ctx context.Context, repo, tag string) (map[string]string, error) {
+	_, config, err := o.describe(ctx, repo, tag)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil || config.Config.Labels == nil {
+		return map[string]string{}, nil
+	}
+	return config.Config.Labels, nil
+}
+
+// ListRepositories fetches the repositories under namespace from the
+// registry catalog (which some registries restrict or disable)
+func (o *OCIClient) ListRepositories(ctx context.Context, namespace string) ([]Repository, error) {
+	names, err := o.listNames(ctx, "", fmt.Sprintf("%s/v2/_catalog?n=%d", o.c.registry, o.c.pageSize), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []Repository
+	for _, name := range names {
+		if rest, ok := strings.CutPrefix(name, namespace+"/"); ok {
+			repos = append(repos, Repository{Namespace: namespace, Name: rest})
+		}
+	}
+	return repos, nil
+}
+
+// DeleteRepository is not supported: the distribution API has no repository deletion
+func (o *OCIClient) DeleteRepository(ctx context.Context, repo string) error {
+	return fmt.Errorf("repository deletion: %w", ErrUnsupported)
+}
+
+// GetTagImages is not supported
+func (o *OCIClient) GetTagImages(ctx context.Context, repo, tag string) ([]ImageDetail, error) {
+	return nil, fmt.Errorf("image layers: %w", ErrUnsupported)
+}
+
+// ListUntagged is not supported: the distribution API cannot list manifests
+// that no tag points to
+func (o *OCIClient) ListUntagged(ctx context.Context, repo string) ([]Manifest, error) {
+	return nil, fmt.Errorf("untagged manifests: %w", ErrUnsupported)
+}
+
+// DeleteUntagged is not supported (see ListUntagged)
+func (o *OCIClient) DeleteUntagged(ctx context.Context, repo string, digests []string) error {
+	return fmt.Errorf("untagged manifests: %w", ErrUnsupported)
+}
+
+// request sends a registry request for repo, answering an authentication
+// challenge once; non-2xx responses are returned as errors
+func (o *OCIClient) request(ctx context.Context, method, repo, endpoint, accept string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		if header := o.authorization(repo); header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+
+		resp, err := o.c.doRequest(req)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+			return resp, nil
+		case resp.StatusCode == http.StatusUnauthorized && attempt == 0:
+			challenge := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+			if err := o.answer(ctx, repo, challenge); err != nil {
+				return nil, err
+			}
+		case resp.StatusCode == http.StatusUnauthorized:
+			resp.Body.Close()
+			return nil, ErrUnauthorized
+		case resp.StatusCode == http.StatusNotFound:
+			resp.Body.Close()
+			return nil, ErrNotFound
+		default:
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, NewAPIError(resp.StatusCode, endpoint, string(bodyBytes))
+		}
+	}
+}
+
+// authorization returns the Authorization header for repo requests
+func (o *OCIClient) authorization(repo string) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if header, ok := o.auth[repo]; ok {
+		return header
+	}
+	if o.token != "" {
+		return "Bearer " + o.token
+	}
+	return ""
+}
+
+// answer stores the Authorization header a WWW-Authenticate challenge asks
+// for: Basic credentials, or a Bearer token from the challenge's realm
+// (requested with the credentials, or anonymously without any)
+func (o *OCIClient) answer(ctx context.Context, repo, challenge string) error {
+	scheme, _, _ := strings.Cut(challenge, " ")
+	params := make(map[string]string)
+	for _, m := range challengeParam.FindAllStringSubmatch(challenge, -1) {
+		params[strings.ToLower(m[1])] = m[2]
+	}
+
+	var header string
+	switch {
+	case o.token != "":
+		// A static token was rejected; there is nothing to exchange it for
+		return ErrUnauthorized
+	case strings.EqualFold(scheme, "basic") && o.username != "":
+		header = "Basic " + base64.StdEncoding.EncodeToString([]byte(o.username+":"+o.password))
+	case strings.EqualFold(scheme, "bearer") && params["realm"] != "":
+		token, err := o.fetchToken(ctx, params)
+		if err != nil {
+			return err
+		}
+		header = "Bearer " + token
+	default:
+		return ErrUnauthorized
+	}
+
+	o.mu.Lock()
+	o.auth[repo] = header
+	o.mu.Unlock()
+	return nil
+}
+
+// fetchToken requests a Bearer token from a challenge's realm for its
+// service and scope
+func (o *OCIClient) fetchToken(ctx context.Context, params map[string]string) (string, error) {
+	endpoint, err := url.Parse(params["realm"])
+	if err != nil {
+		return "", fmt.Errorf("%w: bad token realm %q", ErrInvalidResponse, params["realm"])
+	}
+	query := endpoint.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	if o.username != "" {
+		req.SetBasicAuth(o.username, o.password)
+	}
+
+	resp, err := o.c.send(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrNetworkError, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return "", ErrUnauthorized
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return "", NewAPIError(resp.StatusCode, params["realm"], string(bodyBytes))
+	}
+
+	var tokenResp struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", fmt.Errorf("failed to decode registry token response: %w", err)
+	}
+	if tokenResp.Token == "" {
+		return tokenResp.AccessToken, nil
+	}
+	return tokenResp.Token, nil
+}
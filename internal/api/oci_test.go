@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+func TestOCIDeleteTagsSharedManifest(t *testing.T) {
+	var deleted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deleted = append(deleted, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	o := NewOCIClient(srv.URL)
+	o.c.limiter = rate.NewLimiter(rate.Inf, 1)
+	o.digests["app"] = map[string]string{"a": "sha256:one", "b": "sha256:one", "c": "sha256:one"}
+
+	// A tag sharing its manifest with a tag that stays is refused
+	if err := o.DeleteTag(context.Background(), "app", "a"); !errors.Is(err, ErrSharedVersion) {
+		t.Fatalf("DeleteTag() error = %v, want %v", err, ErrSharedVersion)
+	}
+	if err := o.DeleteTags(context.Background(), "app", []string{"a", "b"}); !errors.Is(err, ErrSharedVersion) {
+		t.Fatalf("DeleteTags(a, b) error = %v, want %v", err, ErrSharedVersion)
+	}
+
+	// All tags of the manifest: it is deleted once
+	if err := o.DeleteTags(context.Background(), "app", []string{"a", "b", "c"}); err != nil {
+		t.Fatalf("DeleteTags(a, b, c) error = %v", err)
+	}
+	if want := []string{"/v2/app/manifests/sha256:one"}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("deleted = %v, want %v", deleted, want)
+	}
+	if len(o.digests["app"]) != 0 {
+		t.Errorf("digests after delete = %v, want none", o.digests["app"])
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import "context"
+
+// Registry is the tag API the cleaner works against
+// *Client implements it for Docker Hub, *GHCRClient for the GitHub Container
+// Registry and *OCIClient for other registries; a backend without a feature
+// returns ErrUnsupported
+type Registry interface {
+	ListTags(ctx context.Context, repo string) ([]Tag, error)
+	ListTagsLimited(ctx context.Context, repo string, limit int) ([]Tag, error)
+	DeleteTag(ctx context.Context, repo, tag string) error
+	GetTagImages(ctx context.Context, repo, tag string) ([]ImageDetail, error)
+	GetImageLabels(ctx context.Context, repo, tag string) (map[string]string, error)
+	ListUntagged(ctx context.Context, repo string) ([]Manifest, error)
+	DeleteUntagged(ctx context.Context, repo string, digests []string) error
+	ListRepositories(ctx context.Context, namespace string) ([]Repository, error)
+	DeleteRepository(ctx context.Context, repo string) error
+
+	// Pace waits on the shared rate limiter without sending a request
+	Pace(ctx context.Context) error
+	// Throttled returns the number of 429 responses received so far
+	Throttled() int64
+	// MaxUsefulConcurrency returns the number of workers the rate limiter can serve
+	MaxUsefulConcurrency() int
+	// Stats returns a snapshot of the API activity so far
+	Stats() Stats
+}
+
+// ManifestDeleter is implemented by registries that delete manifests rather
+// than tags (GHCR package versions, OCI registries). DeleteTags deletes the
+// manifest that all of tags point to, provided no other tag points at it
+// (ErrSharedVersion otherwise), so tags sharing a manifest can be deleted
+// together where DeleteTag refuses each of them.
+type ManifestDeleter interface {
+	DeleteTags(ctx context.Context, repo string, tags []string) error
+}
+
+var (
+	_ ManifestDeleter = (*GHCRClient)(nil)
+	_ ManifestDeleter = (*OCIClient)(nil)
+
+	_ Registry = (*Client)(nil)
+	_ Registry = (*GHCRClient)(nil)
+	_ Registry = (*OCIClient)(nil)
+)
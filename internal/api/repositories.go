@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ListRepositories fetches all repositories in a namespace
+func (c *Client) ListRepositories(ctx context.Context, namespace string) ([]Repository, error) {
+	var all []Repository
+	page := 1
+	guard := newPageGuard()
+
+	for {
+		url := fmt.Sprintf("%s/repositories/%s/?page=%d&page_size=%d", c.baseURL, namespace, page, c.pageSize)
+
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := c.doRequest(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			return nil, ErrNotFound
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized {
+			resp.Body.Close()
+			return nil, ErrUnauthorized
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, NewAPIError(resp.StatusCode, url, string(bodyBytes))
+		}
+
+		var reposResp RepositoriesResponse
+		if err := json.NewDecoder(resp.Body).Decode(&reposResp); err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to decode repositories response: %w", err)
+		}
+		resp.Body.Close()
+
+		all = append(all, reposResp.Results...)
+
+		if reposResp.Next == nil || *reposResp.Next == "" {
+			break
+		}
+
+		if err := guard.check(*reposResp.Next, reposResp.Count, c.pageSize); err != nil {
+			return nil, fmt.Errorf("%w: listing repositories in %s: %s", ErrInvalidResponse, namespace, err)
+		}
+
+		page++
+	}
+
+	return all, nil
+}
+
+// DeleteRepository deletes a repository with all its tags and images
+// This cannot be undone
+func (c *Client) DeleteRepository(ctx context.Context, repo string) error {
+	url := fmt.Sprintf("%s/repositories/%s/", c.baseURL, repo)
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return ErrUnauthorized
+	}
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return NewAPIError(resp.StatusCode, url, string(bodyBytes))
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Stats summarizes a client's API activity
+type Stats struct {
+	Requests    int64         // HTTP requests sent, including repeats after 429s
+	Deletes     int64         // DELETE requests among them
+	Retries     int64         // page fetches repeated after a transient failure
+	RateLimited int64         // 429 responses received
+	Failures    int64         // requests that failed without a response
+	RequestTime time.Duration // total time spent waiting for responses
+	DeleteTime  time.Duration // part of RequestTime spent on DELETE requests
+	LimiterWait time.Duration // total time spent waiting for the rate limiter
+	BackoffWait time.Duration // total time slept before repeating requests
+}
+
+// stats collects Stats from concurrent requests
+type stats struct {
+	mu sync.Mutex
+	s  Stats
+}
+
+// request records one HTTP round trip
+func (st *stats) request(method string, d time.Duration, failed bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.s.Requests++
+	st.s.RequestTime += d
+	if method == http.MethodDelete {
+		st.s.Deletes++
+		st.s.DeleteTime += d
+	}
+	if failed {
+		st.s.Failures++
+	}
+}
+
+// add applies fn to the collected stats
+func (st *stats) add(fn func(s *Stats)) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	fn(&st.s)
+}
+
+// snapshot returns the collected stats
+func (st *stats) snapshot() Stats {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.s
+}
+
+// statsKey is the context key of a WithStats collector
+type statsKey struct{}
+
+// WithStats returns a context whose requests are also counted separately,
+// and a function returning that count. Callers sharing a client (several
+// repositories on one rate limiter) use it to tell their activity apart.
+func WithStats(ctx context.Context) (context.Context, func() Stats) {
+	st := &stats{}
+	return context.WithValue(ctx, statsKey{}, st), st.snapshot
+}
+
+// record applies fn to the client's stats and to the context's, if any
+func (c *Client) record(ctx context.Context, fn func(s *Stats)) {
+	c.stats.add(fn)
+	if st, ok := ctx.Value(statsKey{}).(*stats); ok {
+		st.add(fn)
+	}
+}
+
+// Stats returns a snapshot of the client's API activity so far
+func (c *Client) Stats() Stats {
+	s := c.stats.snapshot()
+	s.RateLimited = c.throttled.Load()
+	return s
+}
+
+// requestTimeoutKey is the context key of a WithRequestTimeout bound
+type requestTimeoutKey struct{}
+
+// WithRequestTimeout returns a context whose HTTP round trips each time out
+// after d with ErrRequestTimeout. Unlike a context deadline, it does not
+// count time spent waiting for the rate limiter or backing off after a 429.
+func WithRequestTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, requestTimeoutKey{}, d)
+}
+
+// send performs one HTTP round trip and records its timing
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	parent := req.Context()
+	timeout, _ := parent.Value(requestTimeoutKey{}).(time.Duration)
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(parent, timeout)
+		req = req.WithContext(ctx)
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	d, failed := time.Since(start), err != nil
+	c.stats.request(req.Method, d, failed)
+	if st, ok := parent.Value(statsKey{}).(*stats); ok {
+		st.request(req.Method, d, failed)
+	}
+
+	if err != nil {
+		cancel()
+		if parent.Err() == nil && errors.Is(req.Context().Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w after %s", ErrRequestTimeout, timeout)
+		}
+		return nil, err
+	}
+	// The timeout also covers reading the body, until the caller closes it
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody releases a round trip's timeout when its body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close implements io.Closer
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// throttle counts a 429 response
+func (c *Client) throttle(ctx context.Context) {
+	c.throttled.Add(1)
+	if st, ok := ctx.Value(statsKey{}).(*stats); ok {
+		st.add(func(s *Stats) { s.RateLimited++ })
+	}
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// dockerHubKeys are the auths entries Docker uses for Docker Hub
+var dockerHubKeys = []string{
+	"https://index.docker.io/v1/",
+	"index.docker.io",
+	"docker.io",
+	"registry-1.docker.io",
+}
+
+// dockerConfig is the subset of ~/.docker/config.json that is read
+type dockerConfig struct {
+	Auths       map[string]dockerAuth `json:"auths"`
+	CredsStore  string                `json:"credsStore"`
+	CredHelpers map[string]string     `json:"credHelpers"`
+}
+
+// dockerAuth is a single auths entry
+type dockerAuth struct {
+	Auth string `json:"auth"`
+}
+
+// DockerConfigPath returns the path of Docker's config.json,
+// respecting the DOCKER_CONFIG environment variable
+func DockerConfigPath() (string, error) {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".docker", "config.json"), nil
+}
+
+// FromDockerConfig reads Docker Hub credentials stored inline by `docker login`
+// Credential helpers are not supported; an error explains how to proceed instead
+func FromDockerConfig() (username, password string, err error) {
+	path, err := DockerConfigPath()
+	if err != nil {
+		return "", "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", "", fmt.Errorf("docker config not found at %s (run `docker login` first)", path)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read docker config: %w", err)
+	}
+
+	var cfg dockerConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return "", "", fmt.Errorf("failed to parse docker config %s: %w", path, err)
+	}
+
+	for _, key := range dockerHubKeys {
+		entry, ok := cfg.Auths[key]
+		if !ok || entry.Auth == "" {
+			continue
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to decode auth for %s: %w", key, err)
+		}
+
+		username, password, ok := strings.Cut(string(decoded), ":")
+		if !ok || username == "" || password == "" {
+			return "", "", fmt.Errorf("malformed auth for %s in %s", key, path)
+		}
+		return username, password, nil
+	}
+
+	for _, key := range dockerHubKeys {
+		if helper, ok := cfg.CredHelpers[key]; ok {
+			return "", "", fmt.Errorf("docker config uses credential helper %q for Docker Hub, which is not supported; pass --username/--password or --token instead", helper)
+		}
+	}
+	if cfg.CredsStore != "" {
+		return "", "", fmt.Errorf("docker config uses credential store %q, which is not supported; pass --username/--password or --token instead", cfg.CredsStore)
+	}
+
+	return "", "", fmt.Errorf("no Docker Hub credentials found in %s", path)
+}
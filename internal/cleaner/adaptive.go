@@ -0,0 +1,73 @@
+package cleaner
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// adaptiveLimit is a semaphore whose size follows an AIMD scheme: it is
+// halved when the API starts returning 429s and grows by one after a full
+// window of unthrottled requests, never exceeding the configured ceiling
+type adaptiveLimit struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	limit   int   // current number of slots
+	ceiling int   // upper bound (--concurrency)
+	active  int   // slots in use
+	streak  int   // unthrottled releases since the last adjustment
+	seen    int64 // throttle count that triggered the last decrease
+	logger  *slog.Logger
+}
+
+// newAdaptiveLimit creates a limiter starting at ceiling slots
+// throttled is the client's current throttle count, so earlier 429s are ignored
+func newAdaptiveLimit(ceiling int, throttled int64, logger *slog.Logger) *adaptiveLimit {
+	if ceiling < 1 {
+		ceiling = 1
+	}
+	l := &adaptiveLimit{
+		limit:   ceiling,
+		ceiling: ceiling,
+		seen:    throttled,
+		logger:  logger,
+	}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire blocks until a slot is free
+func (l *adaptiveLimit) acquire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.active >= l.limit {
+		l.cond.Wait()
+	}
+	l.active++
+}
+
+// release frees a slot and adjusts the limit using the client's throttle
+// count observed after the request. A throttle count is acted on once, so
+// several workers seeing the same 429 halve the limit only once.
+func (l *adaptiveLimit) release(throttled int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+
+	if throttled > l.seen {
+		l.seen = throttled
+		l.streak = 0
+		if l.limit > 1 {
+			l.limit /= 2
+			l.logger.Debug("Throttled by API, lowering concurrency", "concurrency", l.limit)
+		}
+	} else {
+		l.streak++
+		if l.streak >= l.limit && l.limit < l.ceiling {
+			l.limit++
+			l.streak = 0
+			l.logger.Debug("Raising concurrency", "concurrency", l.limit)
+		}
+	}
+
+	l.cond.Broadcast()
+}
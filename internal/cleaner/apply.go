@@ -0,0 +1,176 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+	"github.com/ataraskov/docker-hub-cleaner/internal/plan"
+	"github.com/ataraskov/docker-hub-cleaner/internal/policy"
+	"github.com/ataraskov/docker-hub-cleaner/internal/state"
+)
+
+// ErrStalePlan indicates a repository changed after its plan was made
+var ErrStalePlan = errors.New("repository changed since the plan was made")
+
+// ErrProtectedPlan indicates a planned tag is protected by the in-use,
+// digest-pinned or Protect sources as they read now
+var ErrProtectedPlan = errors.New("planned tag is protected")
+
+// Apply deletes exactly the tags planned for opts.Repository. It refuses
+// with ErrStalePlan unless the repository still holds the same tags, with
+// the same digests, as when the plan was made, and with ErrProtectedPlan if
+// the protection sources, read again, now protect a planned tag. Retention
+// and filter options are not used; in dry-run mode nothing is deleted.
+// If some deletions failed, the result is returned with a *PartialFailureError
+func Apply(ctx context.Context, opts Options, planned plan.Repository) (*CleanResult, error) {
+	opts.setDefaults()
+	// Count this repository's requests apart from others sharing the client
+	ctx, apiStats := api.WithStats(ctx)
+	if opts.missingCredentials() {
+		return nil, fmt.Errorf("either --token or --username/--password must be provided")
+	}
+
+	logger := opts.Logger
+
+	client, info, err := connect(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Fetching tags from repository", "repository", opts.Repository)
+	tags, err := client.ListTags(ctx, opts.Repository)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	if plan.Fingerprint(tags) != planned.Fingerprint {
+		return nil, fmt.Errorf("%w: %s now has %d tags (%d when planned); make and review a new plan",
+			ErrStalePlan, opts.Repository, len(tags), planned.TotalTags)
+	}
+	logger.Info("Repository unchanged since the plan", "repository", opts.Repository, "tags", len(tags))
+
+	// Protections may have changed since the plan was reviewed
+	inUse, err := loadInUse(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	pinned, err := loadPinned(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	protection, err := buildProtection(opts, inUse, pinned)
+	if err != nil {
+		return nil, err
+	}
+
+	var st *state.State
+	if opts.StateFile != "" && !opts.DryRun {
+		st, err = state.Open(opts.StateFile)
+		if err != nil {
+			return nil, err
+		}
+		defer st.Close()
+		logger.Info("State file enabled", "path", opts.StateFile, "recorded", st.Len())
+	}
+
+	c := NewCleaner(Config{
+		Client:        client,
+		DryRun:        opts.DryRun,
+		Logger:        logger,
+		Verbose:       opts.Verbose,
+		State:         st,
+		FailFast:      opts.FailFast,
+		DeleteTimeout: opts.DeleteTimeout,
+		OnAction:      opts.OnAction,
+		Confirm:       opts.Confirm,
+		Concurrency:   opts.Concurrency,
+		AgeField:      opts.AgeField,
+	})
+
+	result, err := c.deletePlanned(ctx, opts.Repository, tags, planned, protection)
+	if err != nil {
+		return nil, fmt.Errorf("applying plan failed: %w", err)
+	}
+	result.RepoInfo = info
+
+	if opts.Verify {
+		if err := c.Verify(ctx, opts.Repository, result); err != nil {
+			logger.Error("Deletion verification failed", "error", err)
+			result.Errors = append(result.Errors, err)
+		}
+	}
+
+	result.APIStats = apiStats()
+	return result, partialFailure(result)
+}
+
+// deletePlanned deletes the planned tags out of the fetched ones (or
+// reports them in dry-run mode); fetched must match the plan's fingerprint.
+// Nothing is deleted if protection (nil for none) keeps a planned tag.
+func (c *Cleaner) deletePlanned(ctx context.Context, repo string, fetched []api.Tag, planned plan.Repository, protection policy.RetentionPolicy) (*CleanResult, error) {
+	result := &CleanResult{Repository: repo, RealisticSize: -1, TotalTags: len(fetched), Fetched: fetched}
+
+	byName := make(map[string]api.Tag, len(fetched))
+	for _, tag := range fetched {
+		result.TotalSize += tag.FullSize
+		byName[tag.Name] = tag
+	}
+
+	deleteAction := ActionDelete
+	if c.dryRun {
+		deleteAction = ActionWouldDelete
+	}
+
+	tagsToDelete := make([]api.Tag, 0, len(planned.Tags))
+	for _, p := range planned.Tags {
+		tag, ok := byName[p.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: planned tag %s is not listed", ErrStalePlan, p.Name)
+		}
+		if protection != nil && protection.ShouldKeep(tag) {
+			return nil, fmt.Errorf("%w: %s; make and review a new plan", ErrProtectedPlan, p.Name)
+		}
+		tagsToDelete = append(tagsToDelete, tag)
+	}
+	result.FilteredTags = len(tagsToDelete)
+
+	tagsToDelete, declined, err := c.confirmDeletions(repo, tagsToDelete, result)
+	if err != nil {
+		return nil, err
+	}
+
+	actionIndex := make(map[string]int, len(tagsToDelete))
+	for _, tag := range tagsToDelete {
+		actionIndex[tag.Name] = len(result.Actions)
+		result.Actions = append(result.Actions, TagAction{Repository: repo, Name: tag.Name, Updated: tag.Time(c.ageField), Size: tag.FullSize, Action: deleteAction})
+	}
+	for _, tag := range declined {
+		result.Actions = append(result.Actions, TagAction{Repository: repo, Name: tag.Name, Updated: tag.Time(c.ageField), Size: tag.FullSize, Action: ActionDeclined})
+		c.emit(result.Actions[len(result.Actions)-1])
+	}
+
+	result.KeptTags = len(fetched) - len(tagsToDelete)
+	result.ReclaimedSize = uniqueSize(tagsToDelete)
+
+	if len(tagsToDelete) == 0 {
+		c.logger.Info("No tags to delete")
+		return result, nil
+	}
+
+	if c.dryRun {
+		c.logger.Info("DRY RUN: Would delete planned tags", "count", len(tagsToDelete))
+		for i, tag := range tagsToDelete {
+			result.DeletedTags = append(result.DeletedTags, tag.Name)
+			c.emit(result.Actions[i])
+			c.logger.Debug("  Would delete", "tag", tag.Name, "updated", tag.LastUpdated, "size", FormatSize(tag.FullSize))
+		}
+		return result, nil
+	}
+
+	c.logger.Info("Deleting planned tags", "count", len(tagsToDelete), "concurrency", c.workers)
+	if err := c.deleteTags(ctx, repo, tagsToDelete, result, actionIndex); err != nil {
+		return result, err
+	}
+	return result, nil
+}
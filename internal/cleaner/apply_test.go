@@ -0,0 +1,44 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/plan"
+)
+
+func TestApplyRefusesProtectedTags(t *testing.T) {
+	tags := testTags("a", "b", "c")
+	planned := plan.Repository{Name: "org/app", Tags: []plan.Tag{{Name: "b"}, {Name: "c"}}}
+
+	for _, tt := range []struct {
+		name    string
+		protect []string
+		wantErr error
+		deleted []string
+	}{
+		{"unprotected", nil, nil, []string{"b", "c"}},
+		{"protected since planned", []string{"c"}, ErrProtectedPlan, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Repository: "org/app", Protect: tt.protect, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+			protection, err := buildProtection(opts, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client := &fakeRegistry{tags: tags}
+			c := newTestCleaner(client, false, 1)
+			_, err = c.deletePlanned(context.Background(), "org/app", tags, planned, protection)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("deletePlanned() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := client.calls(); len(got) != len(tt.deleted) {
+				t.Errorf("deleted = %v, want %v", got, tt.deleted)
+			}
+		})
+	}
+}
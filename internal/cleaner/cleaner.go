@@ -2,35 +2,103 @@ package cleaner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
+	"time"
 
 	"github.com/ataraskov/docker-hub-cleaner/internal/api"
 	"github.com/ataraskov/docker-hub-cleaner/internal/filter"
 	"github.com/ataraskov/docker-hub-cleaner/internal/policy"
+	"github.com/ataraskov/docker-hub-cleaner/internal/snapshot"
 	sortpkg "github.com/ataraskov/docker-hub-cleaner/internal/sort"
+	"github.com/ataraskov/docker-hub-cleaner/internal/state"
 )
 
+// ErrMinRemaining indicates a run would leave fewer tags than the configured floor
+var ErrMinRemaining = errors.New("too few tags would remain")
+
+// ErrDeleteTimeout indicates a request of a tag deletion exceeded the per-tag timeout
+var ErrDeleteTimeout = errors.New("delete timed out")
+
 // Cleaner orchestrates the tag cleaning process
 type Cleaner struct {
-	client  *api.Client
-	filter  filter.TagFilter
-	policy  policy.RetentionPolicy
-	sorter  sortpkg.TagSorter
-	dryRun  bool
-	logger  *slog.Logger
-	verbose bool
+	client   api.Registry
+	filter   filter.TagFilter
+	policy   policy.RetentionPolicy
+	sorter   sortpkg.TagSorter
+	dryRun   bool
+	logger   *slog.Logger
+	verbose  bool
+	tagLimit int
+	shared   string
+	state    *state.State
+	largest  int
+	listKept bool
+	simulate bool
+	labels   bool
+	latestOK bool
+	untagged bool
+	failFast bool
+	explain  bool
+	manifest bool
+	layers   bool
+	minLeft  int
+	minKeep  int
+	workers  int
+	timeout  time.Duration
+	maxDel   int
+	priority string
+	ageField api.AgeField
+	onAction func(TagAction)
+	confirm  ConfirmFunc
+	pinned   policy.RetentionPolicy
+
+	buildPolicy PolicyBuilder
 }
 
+// PolicyBuilder creates a retention policy from the filtered, sorted tags
+// the cleaner acts on (count-based policies depend on that exact slice)
+type PolicyBuilder func(sorted []api.Tag) (policy.RetentionPolicy, error)
+
+// ConfirmFunc asks the operator to approve the deletion of tags from a
+// repository and returns the approved subset
+type ConfirmFunc func(repo string, tags []api.Tag) ([]api.Tag, error)
+
 // Config holds the configuration for the cleaner
 type Config struct {
-	Client  *api.Client
-	Filter  filter.TagFilter
-	Policy  policy.RetentionPolicy
-	Sorter  sortpkg.TagSorter
-	DryRun  bool
-	Logger  *slog.Logger
-	Verbose bool
+	Client        api.Registry
+	Filter        filter.TagFilter
+	Policy        policy.RetentionPolicy
+	Sorter        sortpkg.TagSorter
+	DryRun        bool
+	Logger        *slog.Logger
+	Verbose       bool
+	TagLimit      int                    // stop fetching after this many tags (0 = no limit)
+	Shared        string                 // shared digest handling: SharedDigestsOff, SharedDigestsWarn or SharedDigestsSkip
+	State         *state.State           // optional: records deletions so interrupted runs can resume
+	ShowLargest   int                    // report the N largest deletion candidates
+	ShowRemaining bool                   // report the kept tags in sort order
+	SimulateTime  bool                   // in dry-run, wait on the rate limiter per tag to estimate run time
+	FetchLabels   bool                   // fetch image labels from the registry before filtering (label filters)
+	AllowLatest   bool                   // allow deleting tags that point at the image of the latest tag
+	PruneUntagged bool                   // also report dangling tags and remove untagged manifests
+	FailFast      bool                   // abort on the first deletion error instead of collecting errors
+	ExplainSort   bool                   // log how each tag is parsed by the semver sorter
+	ByManifest    bool                   // evaluate retention per unique manifest and delete all its tags together
+	RealisticSize bool                   // estimate reclaimed size from layers not shared with surviving tags
+	MinRemaining  int                    // abort if fewer tags would remain in the repository (0 = no floor)
+	MinKeep       int                    // never delete the newest N filtered tags in sort order, whatever the policies (0 = off)
+	Concurrency   int                    // maximum concurrent deletions, lowered while the API throttles (default 1)
+	DeleteTimeout time.Duration          // per-request timeout of a tag deletion, excluding rate limiting (0 = only the HTTP client timeout)
+	MaxDelete     int                    // delete at most this many candidates, deferring the rest (0 = no cap)
+	Priority      string                 // with MaxDelete: PriorityAge (oldest first, default) or PrioritySize (largest first)
+	AgeField      api.AgeField           // timestamp used by PriorityAge and reported as TagAction.Updated (default: api.AgeFieldLastUpdated)
+	OnAction      func(TagAction)        // optional: called as each tag's action is decided or carried out (calls are serialized)
+	Confirm       ConfirmFunc            // optional: approves the deletion candidates before anything is deleted (not in dry-run)
+	BuildPolicy   PolicyBuilder          // optional: builds Policy from the filtered, sorted tags
+	Pinned        policy.RetentionPolicy // optional: digest-pinned protection, also applied to untagged manifests
 }
 
 // NewCleaner creates a new cleaner instance
@@ -40,18 +108,44 @@ func NewCleaner(cfg Config) *Cleaner {
 	}
 
 	return &Cleaner{
-		client:  cfg.Client,
-		filter:  cfg.Filter,
-		policy:  cfg.Policy,
-		sorter:  cfg.Sorter,
-		dryRun:  cfg.DryRun,
-		logger:  cfg.Logger,
-		verbose: cfg.Verbose,
+		client:   cfg.Client,
+		filter:   cfg.Filter,
+		policy:   cfg.Policy,
+		sorter:   cfg.Sorter,
+		dryRun:   cfg.DryRun,
+		logger:   cfg.Logger,
+		verbose:  cfg.Verbose,
+		tagLimit: cfg.TagLimit,
+		shared:   cfg.Shared,
+		state:    cfg.State,
+		largest:  cfg.ShowLargest,
+		listKept: cfg.ShowRemaining,
+		simulate: cfg.SimulateTime,
+		labels:   cfg.FetchLabels,
+		latestOK: cfg.AllowLatest,
+		untagged: cfg.PruneUntagged,
+		failFast: cfg.FailFast,
+		explain:  cfg.ExplainSort,
+		manifest: cfg.ByManifest,
+		layers:   cfg.RealisticSize,
+		minLeft:  cfg.MinRemaining,
+		minKeep:  cfg.MinKeep,
+		workers:  cfg.Concurrency,
+		timeout:  cfg.DeleteTimeout,
+		maxDel:   cfg.MaxDelete,
+		priority: cfg.Priority,
+		ageField: cfg.AgeField,
+		onAction: cfg.OnAction,
+		confirm:  cfg.Confirm,
+		pinned:   cfg.Pinned,
+
+		buildPolicy: cfg.BuildPolicy,
 	}
 }
 
 // CleanResult contains the results of a cleaning operation
 type CleanResult struct {
+	Repository    string
 	TotalTags     int
 	FilteredTags  int
 	KeptTags      int
@@ -59,20 +153,72 @@ type CleanResult struct {
 	Errors        []error
 	TotalSize     int64
 	ReclaimedSize int64
+	PolicyCounts  []policy.KeepCount
+	SharedTags    []string // deletion candidates sharing a digest with a kept tag
+	LatestTags    []string // deletion candidates pointing at the image of the latest tag
+	FloorTags     []string // deletion candidates kept because they are among the newest MinKeep tags
+	Largest       []TagSize
+	DanglingTags  []string       // tags reporting no images
+	Untagged      []string       // digests of untagged manifests (deleted or would delete)
+	Changes       *snapshot.Diff // tags added/removed since the previous snapshot
+	Fetched       []api.Tag      // all tags as fetched, before filtering
+	RealisticSize int64          // bytes in layers not shared with surviving tags (-1 if not computed)
+	Unverified    []string       // deleted tags still listed after verification
+	Actions       []TagAction    // per-tag outcome for filtered tags, in sort order
+	KeptTagNames  []string       // kept tags in sort order (only with ShowRemaining)
+	Deferred      []string       // deletion candidates left for a later run by MaxDelete
+	Declined      []string       // deletion candidates the operator did not approve
+
+	// RepoInfo is the repository metadata from the pre-flight check (nil if unavailable)
+	RepoInfo *api.Repository
+	// APIStats is the API activity of this repository's run (requests of
+	// other repositories sharing the client are not included)
+	APIStats api.Stats
+}
+
+// Tag actions
+const (
+	ActionKeep         = "keep"
+	ActionDelete       = "deleted"
+	ActionWouldDelete  = "would delete"
+	ActionFailed       = "error"
+	ActionSkipped      = "skipped"
+	ActionDeferred     = "deferred"
+	ActionDeclined     = "declined"
+	ActionNotAttempted = "not attempted"
+)
+
+// TagAction records what happened to a single tag
+type TagAction struct {
+	Repository string    `json:"repository"`
+	Name       string    `json:"tag"`
+	Updated    time.Time `json:"updated"` // tag timestamp selected by AgeField
+	Size       int64     `json:"size"`
+	Action     string    `json:"action"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// TagSize pairs a tag name with its size
+type TagSize struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
 }
 
 // Clean performs the tag cleaning operation
+// If some deletions failed, the result is returned with a *PartialFailureError
 func (c *Cleaner) Clean(ctx context.Context, repo string) (*CleanResult, error) {
-	result := &CleanResult{}
+	result := &CleanResult{Repository: repo, RealisticSize: -1}
 
 	// Step 1: Fetch all tags
 	c.logger.Info("Fetching tags from repository", "repository", repo)
-	tags, err := c.client.ListTags(ctx, repo)
+	tags, err := c.client.ListTagsLimited(ctx, repo, c.tagLimit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tags: %w", err)
 	}
 
 	result.TotalTags = len(tags)
+	result.Fetched = tags
+	allTags := tags
 	c.logger.Info("Fetched tags", "count", result.TotalTags)
 
 	if result.TotalTags == 0 {
@@ -83,9 +229,19 @@ func (c *Cleaner) Clean(ctx context.Context, repo string) (*CleanResult, error)
 	// Calculate total size
 	for _, tag := range tags {
 		result.TotalSize += tag.FullSize
+		if c.untagged && len(tag.Images) == 0 {
+			result.DanglingTags = append(result.DanglingTags, tag.Name)
+		}
 	}
 
-	// Step 2: Apply filters
+	if len(result.DanglingTags) > 0 {
+		c.logger.Warn("Found tags without images", "count", len(result.DanglingTags), "tags", result.DanglingTags)
+	}
+
+	// Step 2: Apply filters (fetching labels first if a filter needs them)
+	if c.labels {
+		c.fetchLabels(ctx, repo, tags)
+	}
 	if c.filter != nil {
 		filtered := filter.FilterTags(tags, c.filter)
 		result.FilteredTags = len(filtered)
@@ -101,12 +257,32 @@ func (c *Cleaner) Clean(ctx context.Context, repo string) (*CleanResult, error)
 	}
 
 	// Step 3: Sort tags
+	if semverSorter, ok := c.sorter.(*sortpkg.SemverSorter); ok && c.explain {
+		// Explain semver parsing to debug --strip-prefix patterns
+		for _, tag := range tags {
+			cl := semverSorter.Classify(tag.Name)
+			c.logger.Info("Semver parse", "tag", cl.Original, "stripped", cl.Stripped, "version", cl.Version, "valid", cl.Valid)
+		}
+	}
+
 	if c.sorter != nil {
 		tags = c.sorter.Sort(tags)
 		c.logger.Debug("Sorted tags", "count", len(tags))
+		if c.verbose {
+			c.logSortOrder(tags)
+		}
 	}
 
 	// Step 4: Determine which tags to keep/delete
+	if c.buildPolicy != nil {
+		// Build from the exact slice acted on, so filtered-out tags never take count slots
+		p, err := c.buildPolicy(tags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build retention policy: %w", err)
+		}
+		c.policy = p
+	}
+
 	var tagsToKeep, tagsToDelete []api.Tag
 	for _, tag := range tags {
 		if c.policy != nil && c.policy.ShouldKeep(tag) {
@@ -116,11 +292,58 @@ func (c *Cleaner) Clean(ctx context.Context, repo string) (*CleanResult, error)
 		}
 	}
 
+	// Step 4a: Regroup by manifest so a manifest's tags are deleted together
+	if c.manifest {
+		tagsToKeep, tagsToDelete = partitionByManifest(tagsToKeep, tagsToDelete)
+		c.logger.Info("Manifest-level retention", "tags_to_delete", len(tagsToDelete))
+	}
+
+	// Step 4b: Check deletion candidates for digests shared with surviving tags
+	if c.shared != SharedDigestsOff && len(tagsToDelete) > 0 {
+		tagsToKeep, tagsToDelete = c.checkSharedDigests(allTags, tagsToKeep, tagsToDelete, result)
+	}
+
+	// Step 4c: Never delete the image latest points at unless allowed
+	if len(tagsToDelete) > 0 {
+		tagsToKeep, tagsToDelete = c.protectLatest(allTags, tagsToKeep, tagsToDelete, result)
+	}
+
+	// Step 4d: Never delete the newest MinKeep tags
+	if c.minKeep > 0 && len(tagsToDelete) > 0 {
+		tagsToKeep, tagsToDelete = c.keepNewest(tags, tagsToKeep, tagsToDelete, result)
+	}
+
+	// Step 4e: Spend the MaxDelete budget on the highest-priority candidates
+	var deferred []api.Tag
+	if c.maxDel > 0 && len(tagsToDelete) > c.maxDel {
+		tagsToDelete, deferred = c.capDeletions(tagsToDelete)
+		for _, tag := range deferred {
+			result.Deferred = append(result.Deferred, tag.Name)
+		}
+		if len(deferred) > 0 {
+			c.logger.Info("Deletion capped by --max-delete",
+				"max_delete", c.maxDel, "priority", c.priority, "deferred", len(deferred))
+		}
+	}
+
 	result.KeptTags = len(tagsToKeep)
+	result.PolicyCounts = policy.KeepCounts(c.policy, tags)
 
-	// Calculate reclaimed size
-	for _, tag := range tagsToDelete {
-		result.ReclaimedSize += tag.FullSize
+	// Calculate reclaimed size (an image under several tags counts once)
+	result.ReclaimedSize = uniqueSize(tagsToDelete)
+
+	if c.layers && len(tagsToDelete) > 0 {
+		c.logger.Info("Estimating reclaimable size from layers", "tags", len(allTags))
+		size, err := c.realisticSize(ctx, repo, allTags, tagsToDelete)
+		if err != nil {
+			c.logger.Warn("Failed to estimate reclaimable size from layers", "error", err)
+		} else {
+			result.RealisticSize = size
+		}
+	}
+
+	if c.largest > 0 {
+		result.Largest = largestTags(tagsToDelete, c.largest)
 	}
 
 	if c.verbose {
@@ -129,6 +352,10 @@ func (c *Cleaner) Clean(ctx context.Context, repo string) (*CleanResult, error)
 			"to_keep", len(tagsToKeep),
 			"to_delete", len(tagsToDelete))
 
+		for _, pc := range result.PolicyCounts {
+			c.logger.Info("Policy keep count", "policy", pc.Policy, "kept", pc.Kept, "of", len(tags))
+		}
+
 		if len(tagsToKeep) > 0 {
 			c.logger.Debug("Tags to keep", "count", len(tagsToKeep))
 			for _, tag := range tagsToKeep {
@@ -137,6 +364,60 @@ func (c *Cleaner) Clean(ctx context.Context, repo string) (*CleanResult, error)
 		}
 	}
 
+	// Step 4f: Never leave fewer than the configured floor of tags
+	if remaining := result.TotalTags - len(tagsToDelete); len(tagsToDelete) > 0 && remaining < c.minLeft {
+		c.logger.Error("Aborting: deletion would leave too few tags",
+			"remaining", remaining, "min_remaining", c.minLeft, "to_delete", len(tagsToDelete))
+		return result, fmt.Errorf("%w: deleting %d of %d tags would leave %d, below --min-remaining %d (use --allow-empty to override)",
+			ErrMinRemaining, len(tagsToDelete), result.TotalTags, remaining, c.minLeft)
+	}
+
+	// Step 4g: Let the operator approve the deletions
+	tagsToDelete, declined, err := c.confirmDeletions(repo, tagsToDelete, result)
+	if err != nil {
+		return result, err
+	}
+	if len(declined) > 0 {
+		result.KeptTags += len(declined)
+		result.ReclaimedSize = uniqueSize(tagsToDelete)
+		result.RealisticSize = -1 // the estimate included the declined tags
+		if c.largest > 0 {
+			result.Largest = largestTags(tagsToDelete, c.largest)
+		}
+	}
+
+	// Record per-tag actions in sort order
+	actionIndex := make(map[string]int, len(tags))
+	deleteAction := ActionDelete
+	if c.dryRun {
+		deleteAction = ActionWouldDelete
+	}
+	deleting := make(map[string]string, len(tagsToDelete)+len(deferred)+len(declined))
+	for _, tag := range tagsToDelete {
+		deleting[tag.Name] = deleteAction
+	}
+	for _, tag := range deferred {
+		deleting[tag.Name] = ActionDeferred
+	}
+	for _, tag := range declined {
+		deleting[tag.Name] = ActionDeclined
+	}
+	for _, tag := range tags {
+		action := ActionKeep
+		if a, ok := deleting[tag.Name]; ok {
+			action = a
+		}
+		if action == ActionKeep && c.listKept {
+			result.KeptTagNames = append(result.KeptTagNames, tag.Name)
+		}
+		actionIndex[tag.Name] = len(result.Actions)
+		result.Actions = append(result.Actions, TagAction{Repository: repo, Name: tag.Name, Updated: tag.Time(c.ageField), Size: tag.FullSize, Action: action})
+		// Deletions are reported once they happen
+		if action != ActionDelete {
+			c.emit(result.Actions[len(result.Actions)-1])
+		}
+	}
+
 	// Step 5: Delete tags (or report in dry-run mode)
 	if len(tagsToDelete) == 0 {
 		c.logger.Info("No tags to delete")
@@ -145,28 +426,275 @@ func (c *Cleaner) Clean(ctx context.Context, repo string) (*CleanResult, error)
 
 	if c.dryRun {
 		c.logger.Info("DRY RUN: Would delete tags", "count", len(tagsToDelete))
+		start := time.Now()
 		for _, tag := range tagsToDelete {
+			if c.simulate {
+				if err := c.client.Pace(ctx); err != nil {
+					return result, fmt.Errorf("simulated timing interrupted: %w", err)
+				}
+			}
 			result.DeletedTags = append(result.DeletedTags, tag.Name)
-			c.logger.Info("  Would delete", "tag", tag.Name, "updated", tag.LastUpdated, "size", formatSize(tag.FullSize))
+			c.logger.Debug("  Would delete", "tag", tag.Name, "updated", tag.LastUpdated, "size", FormatSize(tag.FullSize))
+		}
+		if c.simulate {
+			c.logger.Info("Estimated deletion time", "duration", time.Since(start).Round(time.Second))
 		}
 	} else {
-		c.logger.Info("Deleting tags", "count", len(tagsToDelete))
-		for _, tag := range tagsToDelete {
-			if err := c.client.DeleteTag(ctx, repo, tag.Name); err != nil {
-				c.logger.Error("Failed to delete tag", "tag", tag.Name, "error", err)
-				result.Errors = append(result.Errors, fmt.Errorf("failed to delete tag %s: %w", tag.Name, err))
-			} else {
-				result.DeletedTags = append(result.DeletedTags, tag.Name)
-				c.logger.Info("  Deleted", "tag", tag.Name, "size", formatSize(tag.FullSize))
-			}
+		c.logger.Info("Deleting tags", "count", len(tagsToDelete), "concurrency", c.workers)
+		if err := c.deleteTags(ctx, repo, tagsToDelete, result, actionIndex); err != nil {
+			return result, err
+		}
+	}
+
+	return result, partialFailure(result)
+}
+
+// confirmDeletions passes the deletion candidates to the Confirm callback,
+// if any, and splits them into approved and declined tags; the declined
+// tags are recorded in the result
+func (c *Cleaner) confirmDeletions(repo string, tags []api.Tag, result *CleanResult) (approved, declined []api.Tag, err error) {
+	if c.confirm == nil || c.dryRun || len(tags) == 0 {
+		return tags, nil, nil
+	}
+
+	approved, err = c.confirm(repo, tags)
+	if err != nil {
+		return nil, nil, fmt.Errorf("confirmation failed: %w", err)
+	}
+
+	ok := make(map[string]bool, len(approved))
+	for _, tag := range approved {
+		ok[tag.Name] = true
+	}
+	approved = make([]api.Tag, 0, len(ok))
+	for _, tag := range tags {
+		if ok[tag.Name] {
+			approved = append(approved, tag)
+		} else {
+			declined = append(declined, tag)
+			result.Declined = append(result.Declined, tag.Name)
+		}
+	}
+	if len(declined) > 0 {
+		c.logger.Info("Deletions declined", "repository", repo, "declined", len(declined), "approved", len(approved))
+	}
+	return approved, declined, nil
+}
+
+// emit passes a tag action to the OnAction callback, if any
+func (c *Cleaner) emit(a TagAction) {
+	if c.onAction != nil {
+		c.onAction(a)
+	}
+}
+
+// Verify re-fetches the tag list and reports deleted tags that are still
+// listed (e.g., deletes that returned success but did not take effect)
+func (c *Cleaner) Verify(ctx context.Context, repo string, result *CleanResult) error {
+	if c.dryRun || len(result.DeletedTags) == 0 {
+		return nil
+	}
+
+	c.logger.Info("Verifying deletions", "count", len(result.DeletedTags))
+	tags, err := c.client.ListTags(ctx, repo)
+	if err != nil {
+		return fmt.Errorf("failed to list tags for verification: %w", err)
+	}
+
+	listed := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		listed[tag.Name] = true
+	}
+
+	for _, name := range result.DeletedTags {
+		if listed[name] {
+			result.Unverified = append(result.Unverified, name)
+			c.logger.Warn("Deleted tag is still listed", "tag", name)
+		}
+	}
+
+	if len(result.Unverified) == 0 {
+		c.logger.Info("All deletions verified")
+	}
+	return nil
+}
+
+// CleanUntagged removes manifests no tag points to (or reports them in
+// dry-run mode), except those the digest-pinned protection keeps. It relies
+// on Docker Hub's image management API, which may be unavailable; in that
+// case an error is returned and nothing is deleted.
+func (c *Cleaner) CleanUntagged(ctx context.Context, repo string, result *CleanResult) error {
+	manifests, err := c.client.ListUntagged(ctx, repo)
+	if err != nil {
+		return fmt.Errorf("failed to list untagged manifests: %w", err)
+	}
+
+	var digests []string
+	for _, m := range manifests {
+		if c.pinned != nil && c.pinned.ShouldKeep(api.Tag{Digest: m.Digest}) {
+			c.logger.Info("Keeping pinned untagged manifest", "digest", m.Digest)
+			continue
+		}
+		digests = append(digests, m.Digest)
+		c.logger.Debug("  Untagged manifest", "digest", m.Digest, "pushed", m.LastPushed)
+	}
+
+	if c.dryRun {
+		result.Untagged = append(result.Untagged, digests...)
+		c.logger.Info("DRY RUN: Would delete untagged manifests", "count", len(digests))
+		return nil
+	}
+
+	if err := c.client.DeleteUntagged(ctx, repo, digests); err != nil {
+		return fmt.Errorf("failed to delete untagged manifests: %w", err)
+	}
+	result.Untagged = append(result.Untagged, digests...)
+	c.logger.Info("Deleted untagged manifests", "count", len(digests))
+	return nil
+}
+
+// largestTags returns the n largest tags by size, largest first
+func largestTags(tags []api.Tag, n int) []TagSize {
+	bySize := make([]api.Tag, len(tags))
+	copy(bySize, tags)
+
+	sort.SliceStable(bySize, func(i, j int) bool {
+		return bySize[i].FullSize > bySize[j].FullSize
+	})
+
+	largest := make([]TagSize, 0, min(n, len(bySize)))
+	for _, tag := range bySize[:min(n, len(bySize))] {
+		largest = append(largest, TagSize{Name: tag.Name, Size: tag.FullSize})
+	}
+	return largest
+}
+
+// keepNewest moves deletion candidates among the first MinKeep sorted tags
+// back to the kept tags, so a repository always keeps its newest tags.
+// With ByManifest the other tags of their manifests are kept too.
+func (c *Cleaner) keepNewest(sorted, tagsToKeep, tagsToDelete []api.Tag, result *CleanResult) ([]api.Tag, []api.Tag) {
+	key := func(tag api.Tag) string { return tag.Name }
+	if c.manifest {
+		key = manifestKey
+	}
+	newest := make(map[string]bool, c.minKeep)
+	for _, tag := range sorted[:min(c.minKeep, len(sorted))] {
+		newest[key(tag)] = true
+	}
+
+	var remaining []api.Tag
+	for _, tag := range tagsToDelete {
+		if !newest[key(tag)] {
+			remaining = append(remaining, tag)
+			continue
+		}
+		result.FloorTags = append(result.FloorTags, tag.Name)
+		tagsToKeep = append(tagsToKeep, tag)
+	}
+
+	if len(result.FloorTags) > 0 {
+		c.logger.Warn("Keeping tags the policies would delete (--min-keep)",
+			"min_keep", c.minKeep, "tags", result.FloorTags)
+	}
+	return tagsToKeep, remaining
+}
+
+// capDeletions splits candidates into the c.maxDel tags to delete this run
+// and the deferred rest: oldest first for PriorityAge, largest first for
+// PrioritySize (ties keep sort order). With ByManifest the budget counts
+// manifests, so the tags of a manifest are deleted or deferred together.
+func (c *Cleaner) capDeletions(tags []api.Tag) (selected, deferred []api.Tag) {
+	ordered := make([]api.Tag, len(tags))
+	copy(ordered, tags)
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if c.priority == PrioritySize {
+			return ordered[i].FullSize > ordered[j].FullSize
+		}
+		return ordered[i].Time(c.ageField).Before(ordered[j].Time(c.ageField))
+	})
+
+	if !c.manifest {
+		return ordered[:c.maxDel], ordered[c.maxDel:]
+	}
+
+	// A manifest takes its place at its first (highest-priority) tag
+	budget := make(map[string]bool, c.maxDel)
+	for _, tag := range ordered {
+		key := manifestKey(tag)
+		if !budget[key] && len(budget) < c.maxDel {
+			budget[key] = true
+		}
+		if budget[key] {
+			selected = append(selected, tag)
+		} else {
+			deferred = append(deferred, tag)
+		}
+	}
+	return selected, deferred
+}
+
+// maxSortOrderLog caps how many tags are listed when logging the sort order
+const maxSortOrderLog = 200
+
+// logSortOrder logs the computed sort order for debugging retention boundaries
+func (c *Cleaner) logSortOrder(tags []api.Tag) {
+	versioner, _ := c.sorter.(sortpkg.Versioner)
+
+	for i, tag := range tags {
+		if i == maxSortOrderLog {
+			c.logger.Debug("  ... sort order truncated", "remaining", len(tags)-i)
+			break
+		}
+		attrs := []any{"index", i, "tag", tag.Name}
+		if versioner != nil {
+			attrs = append(attrs, "version", versioner.Version(tag.Name))
+		}
+		attrs = append(attrs, "updated", tag.LastUpdated)
+		c.logger.Debug("  Sorted", attrs...)
+	}
+}
+
+// checkSharedDigests warns about (or spares) deletion candidates whose image
+// digest is also referenced by a tag that survives the run
+func (c *Cleaner) checkSharedDigests(allTags, tagsToKeep, tagsToDelete []api.Tag, result *CleanResult) ([]api.Tag, []api.Tag) {
+	deleting := make(map[string]bool, len(tagsToDelete))
+	for _, tag := range tagsToDelete {
+		deleting[tag.Name] = true
+	}
+
+	// Surviving tags include those excluded by filters, not only the kept ones
+	var surviving []api.Tag
+	for _, tag := range allTags {
+		if !deleting[tag.Name] {
+			surviving = append(surviving, tag)
+		}
+	}
+	idx := newDigestIndex(surviving)
+
+	var remaining []api.Tag
+	for _, tag := range tagsToDelete {
+		shared := idx.sharedWith(tag)
+		if len(shared) == 0 {
+			remaining = append(remaining, tag)
+			continue
+		}
+
+		result.SharedTags = append(result.SharedTags, tag.Name)
+		if c.shared == SharedDigestsSkip {
+			c.logger.Warn("Sparing tag sharing a digest with a kept tag", "tag", tag.Name, "shared_with", shared)
+			tagsToKeep = append(tagsToKeep, tag)
+		} else {
+			c.logger.Warn("Deleting tag sharing a digest with a kept tag", "tag", tag.Name, "shared_with", shared)
+			remaining = append(remaining, tag)
 		}
 	}
 
-	return result, nil
+	return tagsToKeep, remaining
 }
 
-// formatSize formats a size in bytes to a human-readable string
-func formatSize(bytes int64) string {
+// FormatSize formats a size in bytes to a human-readable string
+func FormatSize(bytes int64) string {
 	const unit = 1024
 	if bytes < unit {
 		return fmt.Sprintf("%d B", bytes)
@@ -0,0 +1,204 @@
+package cleaner
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+	"github.com/ataraskov/docker-hub-cleaner/internal/filter"
+	"github.com/ataraskov/docker-hub-cleaner/internal/policy"
+)
+
+func TestActionsReportSelectedAgeField(t *testing.T) {
+	updated := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	pushed := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tags := []api.Tag{
+		{Name: "pushed", LastUpdated: updated, TagLastPushed: pushed},
+		{Name: "unknown", LastUpdated: updated}, // falls back to last_updated
+	}
+
+	for _, tt := range []struct {
+		field api.AgeField
+		want  map[string]time.Time
+	}{
+		{api.AgeFieldLastUpdated, map[string]time.Time{"pushed": updated, "unknown": updated}},
+		{api.AgeFieldLastPushed, map[string]time.Time{"pushed": pushed, "unknown": updated}},
+	} {
+		t.Run(string(tt.field), func(t *testing.T) {
+			c := NewCleaner(Config{
+				Client:   &fakeRegistry{tags: tags},
+				Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+				DryRun:   true,
+				AgeField: tt.field,
+			})
+			result, err := c.Clean(context.Background(), "repo")
+			if err != nil {
+				t.Fatalf("Clean() error = %v", err)
+			}
+			for _, a := range result.Actions {
+				if !a.Updated.Equal(tt.want[a.Name]) {
+					t.Errorf("%s: Updated = %v, want %v", a.Name, a.Updated, tt.want[a.Name])
+				}
+			}
+		})
+	}
+}
+
+func TestActionsCarryRepository(t *testing.T) {
+	var emitted []TagAction
+	c := NewCleaner(Config{
+		Client:   &fakeRegistry{tags: testTags("a", "b")},
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		OnAction: func(a TagAction) { emitted = append(emitted, a) },
+	})
+	if _, err := c.Clean(context.Background(), "org/app"); err != nil {
+		t.Fatalf("Clean() error = %v", err)
+	}
+
+	if len(emitted) != 2 {
+		t.Fatalf("emitted %d actions, want 2", len(emitted))
+	}
+	for _, a := range emitted {
+		if a.Repository != "org/app" {
+			t.Errorf("%s: Repository = %q, want %q", a.Name, a.Repository, "org/app")
+		}
+	}
+}
+
+func TestMinAgeTagsAreNeverDeleted(t *testing.T) {
+	// Without a policy every tag that passes the filters is deleted
+	days := map[string]int{"fresh": 0, "yesterday": 1, "old": 5, "older": 30}
+	client := &fakeRegistry{tags: agedTags(days, "fresh", "yesterday", "old", "older")}
+	c := NewCleaner(Config{
+		Client: client,
+		Filter: filter.NewMinAgeFilter(48*time.Hour, api.AgeFieldLastUpdated),
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+
+	result, err := c.Clean(context.Background(), "repo")
+	if err != nil {
+		t.Fatalf("Clean() error = %v", err)
+	}
+	if got, want := client.calls(), []string{"old", "older"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DeleteTag calls = %v, want %v", got, want)
+	}
+	for _, name := range []string{"fresh", "yesterday"} {
+		if a, ok := actions(result)[name]; ok {
+			t.Errorf("%s: action %q, want it left out as too young", name, a)
+		}
+	}
+}
+
+func TestMaxDeletePriority(t *testing.T) {
+	// Newest first: a..e; b/c and d/e share a manifest
+	tags := testTags("a", "b", "c", "d", "e")
+	sizes := []int64{5, 4, 4, 3, 3}
+	digests := []string{"sha256:m3", "sha256:m2", "sha256:m2", "sha256:m1", "sha256:m1"}
+	for i := range tags {
+		tags[i].FullSize = sizes[i]
+		tags[i].Digest = digests[i]
+	}
+
+	for _, tt := range []struct {
+		name       string
+		priority   string
+		byManifest bool
+		maxDelete  int
+		deferred   []string
+	}{
+		{"age", PriorityAge, false, 2, []string{"a", "b", "c"}},
+		{"size", PrioritySize, false, 2, []string{"c", "d", "e"}},
+		// Manifests count once and are never split between runs
+		{"age by manifest", PriorityAge, true, 1, []string{"a", "b", "c"}},
+		{"size by manifest", PrioritySize, true, 2, []string{"d", "e"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCleaner(Config{
+				Client:     &fakeRegistry{tags: tags},
+				Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+				DryRun:     true,
+				MaxDelete:  tt.maxDelete,
+				Priority:   tt.priority,
+				ByManifest: tt.byManifest,
+			})
+			result, err := c.Clean(context.Background(), "repo")
+			if err != nil {
+				t.Fatalf("Clean() error = %v", err)
+			}
+
+			got := actions(result)
+			deferred := make(map[string]bool, len(tt.deferred))
+			for _, name := range tt.deferred {
+				deferred[name] = true
+			}
+			for _, tag := range tags {
+				want := ActionWouldDelete
+				if deferred[tag.Name] {
+					want = ActionDeferred
+				}
+				if got[tag.Name] != want {
+					t.Errorf("%s: action = %q, want %q", tag.Name, got[tag.Name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestMinKeepByManifestKeepsWholeManifest(t *testing.T) {
+	tags := testTags("a", "b", "c")
+	tags[0].Digest, tags[1].Digest, tags[2].Digest = "sha256:new", "sha256:new", "sha256:old"
+
+	c := NewCleaner(Config{
+		Client:     &fakeRegistry{tags: tags},
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		DryRun:     true,
+		MinKeep:    1,
+		ByManifest: true,
+	})
+	result, err := c.Clean(context.Background(), "repo")
+	if err != nil {
+		t.Fatalf("Clean() error = %v", err)
+	}
+
+	want := map[string]string{"a": ActionKeep, "b": ActionKeep, "c": ActionWouldDelete}
+	if got := actions(result); !reflect.DeepEqual(got, want) {
+		t.Errorf("actions = %v, want %v", got, want)
+	}
+}
+
+// untaggedRegistry serves untagged manifests and records their deletion
+type untaggedRegistry struct {
+	fakeRegistry
+	untagged []api.Manifest
+	pruned   []string
+}
+
+func (u *untaggedRegistry) ListUntagged(ctx context.Context, repo string) ([]api.Manifest, error) {
+	return u.untagged, nil
+}
+
+func (u *untaggedRegistry) DeleteUntagged(ctx context.Context, repo string, digests []string) error {
+	u.pruned = append(u.pruned, digests...)
+	return nil
+}
+
+func TestCleanUntaggedKeepsPinnedDigests(t *testing.T) {
+	client := &untaggedRegistry{untagged: []api.Manifest{{Digest: "sha256:pinned"}, {Digest: "sha256:loose"}}}
+	c := NewCleaner(Config{
+		Client: client,
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Pinned: policy.NewDigestPinnedPolicy([]string{"sha256:pinned"}),
+	})
+
+	result := &CleanResult{}
+	if err := c.CleanUntagged(context.Background(), "org/app", result); err != nil {
+		t.Fatalf("CleanUntagged() error = %v", err)
+	}
+	if want := []string{"sha256:loose"}; !reflect.DeepEqual(client.pruned, want) || !reflect.DeepEqual(result.Untagged, want) {
+		t.Errorf("pruned = %v, Untagged = %v, want %v", client.pruned, result.Untagged, want)
+	}
+}
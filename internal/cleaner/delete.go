@@ -0,0 +1,168 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// deleteTags deletes tags using up to c.workers concurrent requests, backing
+// off while the API throttles. Tags sharing a manifest on a registry that
+// deletes manifests are deleted together (see deleteGroups). With fail-fast,
+// no new deletions start after the first failure, deletions in flight are
+// cancelled, the tags never tried are reported as not attempted and that
+// failure is returned.
+func (c *Cleaner) deleteTags(ctx context.Context, repo string, tags []api.Tag, result *CleanResult, actionIndex map[string]int) error {
+	limit := newAdaptiveLimit(c.workers, c.client.Throttled(), c.logger)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+
+	var pending []api.Tag
+	for _, tag := range tags {
+		if c.state != nil && c.state.Deleted(repo, tag.Name) {
+			c.logger.Debug("  Skipping (already deleted per state file)", "tag", tag.Name)
+			result.Actions[actionIndex[tag.Name]].Action = ActionSkipped
+			c.emit(result.Actions[actionIndex[tag.Name]])
+			continue
+		}
+		pending = append(pending, tag)
+	}
+
+	groups := c.deleteGroups(pending)
+	for i, group := range groups {
+		limit.acquire()
+		mu.Lock()
+		stop := firstErr != nil
+		mu.Unlock()
+		if stop {
+			limit.release(c.client.Throttled())
+			c.abandon(slices.Concat(groups[i:]...), result, actionIndex, &mu)
+			break
+		}
+
+		wg.Add(1)
+		go func(group []api.Tag) {
+			defer wg.Done()
+			// Free the slot only once the outcome is recorded, so the loop
+			// sees a fail-fast failure before starting another deletion
+			defer func() { limit.release(c.client.Throttled()) }()
+			err := c.deleteGroup(ctx, repo, group)
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, tag := range group {
+				if err != nil {
+					c.logger.Error("Failed to delete tag", "tag", tag.Name, "error", err)
+					tagErr := fmt.Errorf("failed to delete tag %s: %w", tag.Name, err)
+					result.Errors = append(result.Errors, tagErr)
+					result.Actions[actionIndex[tag.Name]].Action = ActionFailed
+					result.Actions[actionIndex[tag.Name]].Error = tagErr.Error()
+					c.emit(result.Actions[actionIndex[tag.Name]])
+					if c.failFast && firstErr == nil {
+						firstErr = tagErr
+						cancel()
+					}
+					continue
+				}
+
+				result.DeletedTags = append(result.DeletedTags, tag.Name)
+				c.emit(result.Actions[actionIndex[tag.Name]])
+				c.logger.Debug("  Deleted", "tag", tag.Name, "size", FormatSize(tag.FullSize))
+				if c.state != nil {
+					if err := c.state.Record(repo, tag.Name); err != nil {
+						c.logger.Warn("Failed to record deletion in state file", "tag", tag.Name, "error", err)
+					}
+				}
+			}
+		}(group)
+	}
+
+	wg.Wait()
+
+	// Workers finish in any order; report deletions in sort order
+	order := make(map[string]int, len(tags))
+	for i, tag := range tags {
+		order[tag.Name] = i
+	}
+	sort.SliceStable(result.DeletedTags, func(i, j int) bool {
+		return order[result.DeletedTags[i]] < order[result.DeletedTags[j]]
+	})
+	return firstErr
+}
+
+// abandon reports tags left undeleted after a fail-fast failure as not attempted
+func (c *Cleaner) abandon(tags []api.Tag, result *CleanResult, actionIndex map[string]int, mu *sync.Mutex) {
+	mu.Lock()
+	defer mu.Unlock()
+	n := 0
+	for _, tag := range tags {
+		a := &result.Actions[actionIndex[tag.Name]]
+		if a.Action != ActionDelete {
+			continue
+		}
+		a.Action = ActionNotAttempted
+		c.emit(*a)
+		n++
+	}
+	c.logger.Warn("Stopped deleting after the first failure (--fail-fast)", "not_attempted", n)
+}
+
+// deleteGroups splits tags into the units deleted by one request: a single
+// tag, or on a registry that deletes manifests (api.ManifestDeleter) all
+// tags sharing a digest, which it refuses to delete one by one
+func (c *Cleaner) deleteGroups(tags []api.Tag) [][]api.Tag {
+	_, byManifest := c.client.(api.ManifestDeleter)
+
+	var groups [][]api.Tag
+	index := make(map[string]int)
+	for _, tag := range tags {
+		if !byManifest || tag.Digest == "" {
+			groups = append(groups, []api.Tag{tag})
+			continue
+		}
+		if i, ok := index[tag.Digest]; ok {
+			groups[i] = append(groups[i], tag)
+			continue
+		}
+		index[tag.Digest] = len(groups)
+		groups = append(groups, []api.Tag{tag})
+	}
+	return groups
+}
+
+// deleteGroup deletes a group of tags (see deleteGroups), bounding each
+// HTTP round trip by the per-tag timeout if set; rate limiter waits and 429
+// backoff are not counted.
+// A timeout is reported as ErrDeleteTimeout rather than the underlying error.
+func (c *Cleaner) deleteGroup(ctx context.Context, repo string, group []api.Tag) error {
+	if c.timeout > 0 {
+		ctx = api.WithRequestTimeout(ctx, c.timeout)
+	}
+
+	var err error
+	if md, ok := c.client.(api.ManifestDeleter); ok && len(group) > 1 {
+		names := make([]string, len(group))
+		for i, tag := range group {
+			names[i] = tag.Name
+		}
+		err = md.DeleteTags(ctx, repo, names)
+	} else {
+		err = c.client.DeleteTag(ctx, repo, group[0].Name)
+	}
+
+	if c.timeout > 0 && errors.Is(err, api.ErrRequestTimeout) {
+		return fmt.Errorf("%w after %s", ErrDeleteTimeout, c.timeout)
+	}
+	return err
+}
@@ -0,0 +1,270 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+	"github.com/ataraskov/docker-hub-cleaner/internal/policy"
+	sortpkg "github.com/ataraskov/docker-hub-cleaner/internal/sort"
+)
+
+// fakeRegistry serves a fixed tag list and records deletions
+// Methods the cleaner does not call in these tests panic via the nil Registry
+type fakeRegistry struct {
+	api.Registry
+
+	tags   []api.Tag
+	delete func(ctx context.Context, tag string) error // optional: result of each deletion
+
+	mu      sync.Mutex
+	deleted []string // tag names passed to DeleteTag, in call order
+}
+
+func (f *fakeRegistry) ListTagsLimited(ctx context.Context, repo string, limit int) ([]api.Tag, error) {
+	return f.tags, nil
+}
+
+func (f *fakeRegistry) DeleteTag(ctx context.Context, repo, tag string) error {
+	f.mu.Lock()
+	f.deleted = append(f.deleted, tag)
+	f.mu.Unlock()
+	if f.delete != nil {
+		return f.delete(ctx, tag)
+	}
+	return nil
+}
+
+func (f *fakeRegistry) Throttled() int64 { return 0 }
+
+func (f *fakeRegistry) calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.deleted...)
+}
+
+// testTags returns tags with the given names, newest first
+func testTags(names ...string) []api.Tag {
+	now := time.Now()
+	tags := make([]api.Tag, len(names))
+	for i, name := range names {
+		tags[i] = api.Tag{Name: name, LastUpdated: now.Add(-time.Duration(i) * time.Hour)}
+	}
+	return tags
+}
+
+// actions maps each tag to its recorded action
+func actions(result *CleanResult) map[string]string {
+	m := make(map[string]string, len(result.Actions))
+	for _, a := range result.Actions {
+		m[a.Name] = a.Action
+	}
+	return m
+}
+
+func newTestCleaner(client api.Registry, failFast bool, concurrency int) *Cleaner {
+	return NewCleaner(Config{
+		Client:      client,
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		FailFast:    failFast,
+		Concurrency: concurrency,
+	})
+}
+
+var errDelete = errors.New("boom")
+
+func TestDeleteTagsFailFastStopsAndReportsUnattempted(t *testing.T) {
+	client := &fakeRegistry{
+		tags: testTags("a", "b", "c", "d"),
+		delete: func(ctx context.Context, tag string) error {
+			if tag == "b" {
+				return errDelete
+			}
+			return nil
+		},
+	}
+
+	result, err := newTestCleaner(client, true, 1).Clean(context.Background(), "repo")
+	if !errors.Is(err, errDelete) {
+		t.Fatalf("Clean() error = %v, want %v", err, errDelete)
+	}
+
+	if got, want := client.calls(), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DeleteTag calls = %v, want %v", got, want)
+	}
+	want := map[string]string{
+		"a": ActionDelete,
+		"b": ActionFailed,
+		"c": ActionNotAttempted,
+		"d": ActionNotAttempted,
+	}
+	if got := actions(result); !reflect.DeepEqual(got, want) {
+		t.Errorf("actions = %v, want %v", got, want)
+	}
+	if got, want := result.DeletedTags, []string{"a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DeletedTags = %v, want %v", got, want)
+	}
+}
+
+func TestDeleteTagsFailFastCancelsInFlight(t *testing.T) {
+	started := make(chan struct{})
+	client := &fakeRegistry{
+		tags: testTags("slow", "bad"),
+		delete: func(ctx context.Context, tag string) error {
+			if tag == "bad" {
+				<-started
+				return errDelete
+			}
+			close(started)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+				return nil
+			}
+		},
+	}
+
+	result, err := newTestCleaner(client, true, 2).Clean(context.Background(), "repo")
+	if !errors.Is(err, errDelete) {
+		t.Fatalf("Clean() error = %v, want %v", err, errDelete)
+	}
+
+	for _, a := range result.Actions {
+		if a.Name == "slow" && a.Action != ActionFailed {
+			t.Errorf("in-flight deletion action = %q, want %q (cancelled)", a.Action, ActionFailed)
+		}
+	}
+	var cancelled bool
+	for _, e := range result.Errors {
+		if errors.Is(e, context.Canceled) {
+			cancelled = true
+		}
+	}
+	if !cancelled {
+		t.Errorf("Errors = %v, want the in-flight deletion cancelled", result.Errors)
+	}
+}
+
+func TestDeleteTagsWithoutFailFastAttemptsAll(t *testing.T) {
+	client := &fakeRegistry{
+		tags: testTags("a", "b", "c", "d"),
+		delete: func(ctx context.Context, tag string) error {
+			if tag == "b" || tag == "c" {
+				return errDelete
+			}
+			return nil
+		},
+	}
+
+	result, err := newTestCleaner(client, false, 1).Clean(context.Background(), "repo")
+	var partial *PartialFailureError
+	if !errors.As(err, &partial) {
+		t.Fatalf("Clean() error = %v, want a *PartialFailureError", err)
+	}
+
+	if got, want := client.calls(), []string{"a", "b", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DeleteTag calls = %v, want %v", got, want)
+	}
+	want := map[string]string{
+		"a": ActionDelete,
+		"b": ActionFailed,
+		"c": ActionFailed,
+		"d": ActionDelete,
+	}
+	if got := actions(result); !reflect.DeepEqual(got, want) {
+		t.Errorf("actions = %v, want %v", got, want)
+	}
+	if len(result.Errors) != 2 {
+		t.Errorf("Errors = %v, want 2", result.Errors)
+	}
+}
+
+func TestDeleteTagsUsesOriginalTagName(t *testing.T) {
+	normalize, err := sortpkg.NewRegexReplace(`_=>.`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sorter, err := sortpkg.NewSemverSorter(`^release-`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sorter.WithNormalize(normalize)
+
+	client := &fakeRegistry{tags: testTags("release-1_2_3", "release-1_2_4")}
+	c := NewCleaner(Config{
+		Client: client,
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Sorter: sorter,
+		BuildPolicy: func(sorted []api.Tag) (policy.RetentionPolicy, error) {
+			return policy.NewCountRetentionPolicy(1, sorted), nil
+		},
+	})
+
+	if _, err := c.Clean(context.Background(), "repo"); err != nil {
+		t.Fatalf("Clean() error = %v", err)
+	}
+	if got, want := client.calls(), []string{"release-1_2_3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DeleteTag calls = %v, want %v", got, want)
+	}
+}
+
+func TestDeleteTagsReportsDeletionsInSortOrder(t *testing.T) {
+	names := []string{"a", "b", "c", "d", "e", "f"}
+	client := &fakeRegistry{
+		tags: testTags(names...),
+		delete: func(ctx context.Context, tag string) error {
+			// Earlier tags finish last
+			time.Sleep(time.Duration('g'-tag[0]) * time.Millisecond)
+			return nil
+		},
+	}
+
+	result, err := newTestCleaner(client, false, len(names)).Clean(context.Background(), "repo")
+	if err != nil {
+		t.Fatalf("Clean() error = %v", err)
+	}
+	if !reflect.DeepEqual(result.DeletedTags, names) {
+		t.Errorf("DeletedTags = %v, want %v", result.DeletedTags, names)
+	}
+}
+
+// manifestRegistry deletes manifests: tags sharing a digest go together
+type manifestRegistry struct {
+	fakeRegistry
+	groups [][]string
+}
+
+func (m *manifestRegistry) DeleteTags(ctx context.Context, repo string, tags []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.groups = append(m.groups, tags)
+	return nil
+}
+
+func TestDeleteTagsGroupsSharedManifests(t *testing.T) {
+	tags := testTags("a", "b", "c")
+	tags[0].Digest, tags[1].Digest, tags[2].Digest = "sha256:one", "sha256:one", "sha256:two"
+
+	client := &manifestRegistry{fakeRegistry: fakeRegistry{tags: tags}}
+	result, err := newTestCleaner(client, false, 1).Clean(context.Background(), "repo")
+	if err != nil {
+		t.Fatalf("Clean() error = %v", err)
+	}
+
+	if want := [][]string{{"a", "b"}}; !reflect.DeepEqual(client.groups, want) {
+		t.Errorf("DeleteTags() calls = %v, want %v", client.groups, want)
+	}
+	if want := []string{"c"}; !reflect.DeepEqual(client.calls(), want) {
+		t.Errorf("DeleteTag() calls = %v, want %v", client.calls(), want)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(result.DeletedTags, want) {
+		t.Errorf("DeletedTags = %v, want %v", result.DeletedTags, want)
+	}
+}
@@ -0,0 +1,132 @@
+package cleaner
+
+import "github.com/ataraskov/docker-hub-cleaner/internal/api"
+
+// Shared digest handling modes
+const (
+	// SharedDigestsOff ignores digests shared between tags
+	SharedDigestsOff = ""
+	// SharedDigestsWarn logs deletion candidates sharing a digest with a kept tag
+	SharedDigestsWarn = "warn"
+	// SharedDigestsSkip spares deletion candidates sharing a digest with a kept tag
+	SharedDigestsSkip = "skip"
+)
+
+// digestIndex maps image digests to the names of tags referencing them
+type digestIndex map[string][]string
+
+// newDigestIndex builds a digest index over the given tags
+func newDigestIndex(tags []api.Tag) digestIndex {
+	idx := make(digestIndex)
+	for _, tag := range tags {
+		for _, image := range tag.Images {
+			if image.Digest != "" {
+				idx[image.Digest] = append(idx[image.Digest], tag.Name)
+			}
+		}
+	}
+	return idx
+}
+
+// sharedWith returns the names of tags in idx sharing any digest with tag
+func (idx digestIndex) sharedWith(tag api.Tag) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, image := range tag.Images {
+		for _, name := range idx[image.Digest] {
+			if name != tag.Name && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// manifestKey identifies the manifest a tag points to
+// Falls back to the first image digest, then to the tag name itself
+func manifestKey(tag api.Tag) string {
+	if tag.Digest != "" {
+		return tag.Digest
+	}
+	for _, image := range tag.Images {
+		if image.Digest != "" {
+			return image.Digest
+		}
+	}
+	return "tag:" + tag.Name
+}
+
+// partitionByManifest regroups a keep/delete partition at the manifest level:
+// a manifest is kept if any of its tags is kept, and then all its tags are kept.
+// Deletion candidates left over are tags of manifests nothing keeps.
+func partitionByManifest(tagsToKeep, tagsToDelete []api.Tag) ([]api.Tag, []api.Tag) {
+	kept := make(map[string]bool, len(tagsToKeep))
+	for _, tag := range tagsToKeep {
+		kept[manifestKey(tag)] = true
+	}
+
+	var remaining []api.Tag
+	for _, tag := range tagsToDelete {
+		if kept[manifestKey(tag)] {
+			tagsToKeep = append(tagsToKeep, tag)
+		} else {
+			remaining = append(remaining, tag)
+		}
+	}
+	return tagsToKeep, remaining
+}
+
+// uniqueSize sums tag sizes counting each manifest only once
+func uniqueSize(tags []api.Tag) int64 {
+	seen := make(map[string]bool, len(tags))
+	var size int64
+	for _, tag := range tags {
+		key := manifestKey(tag)
+		if !seen[key] {
+			seen[key] = true
+			size += tag.FullSize
+		}
+	}
+	return size
+}
+
+// latestTag is the tag whose image is protected from deletion by default
+const latestTag = "latest"
+
+// protectLatest finds deletion candidates pointing at the image of the
+// latest tag and, unless deleting it is allowed, keeps them instead.
+// Without a latest tag among allTags this is a no-op.
+func (c *Cleaner) protectLatest(allTags, tagsToKeep, tagsToDelete []api.Tag, result *CleanResult) ([]api.Tag, []api.Tag) {
+	var latest *api.Tag
+	for i := range allTags {
+		if allTags[i].Name == latestTag {
+			latest = &allTags[i]
+			break
+		}
+	}
+	if latest == nil {
+		return tagsToKeep, tagsToDelete
+	}
+	key := manifestKey(*latest)
+
+	var remaining []api.Tag
+	for _, tag := range tagsToDelete {
+		if manifestKey(tag) != key {
+			remaining = append(remaining, tag)
+			continue
+		}
+
+		result.LatestTags = append(result.LatestTags, tag.Name)
+		if c.latestOK {
+			c.logger.Warn("DELETING THE CURRENT LATEST IMAGE", "tag", tag.Name, "digest", latest.Digest)
+			remaining = append(remaining, tag)
+		} else {
+			c.logger.Warn("Sparing tag pointing at the current latest image (use --allow-delete-latest to delete it)",
+				"tag", tag.Name, "digest", latest.Digest)
+			tagsToKeep = append(tagsToKeep, tag)
+		}
+	}
+
+	return tagsToKeep, remaining
+}
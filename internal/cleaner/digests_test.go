@@ -0,0 +1,78 @@
+package cleaner
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+	"github.com/ataraskov/docker-hub-cleaner/internal/filter"
+	"github.com/ataraskov/docker-hub-cleaner/internal/policy"
+)
+
+// sharedDigestTags returns tags newest first where v1 shares an image with
+// stable (filtered out), v0 with v3 (kept), and v2 only with v2-alias
+func sharedDigestTags() []api.Tag {
+	images := func(digests ...string) []api.Image {
+		out := make([]api.Image, len(digests))
+		for i, d := range digests {
+			out[i] = api.Image{Digest: d}
+		}
+		return out
+	}
+	tags := testTags("v3", "v2", "v2-alias", "v1", "stable", "v0")
+	for i, digests := range [][]string{
+		{"sha256:c"}, {"sha256:b"}, {"sha256:b"}, {"sha256:a"}, {"sha256:a"}, {"sha256:d", "sha256:c"},
+	} {
+		tags[i].Images = images(digests...)
+	}
+	return tags
+}
+
+func TestSharedDigests(t *testing.T) {
+	for _, tt := range []struct {
+		mode        string
+		wantDeleted []string
+		wantShared  []string
+	}{
+		{SharedDigestsOff, []string{"v0", "v1", "v2", "v2-alias"}, nil},
+		{SharedDigestsWarn, []string{"v0", "v1", "v2", "v2-alias"}, []string{"v1", "v0"}},
+		{SharedDigestsSkip, []string{"v2", "v2-alias"}, []string{"v1", "v0"}},
+	} {
+		name := tt.mode
+		if name == SharedDigestsOff {
+			name = "off"
+		}
+		t.Run(name, func(t *testing.T) {
+			tags := sharedDigestTags()
+			f, err := filter.NewRegexFilter(`^v`, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := &fakeRegistry{tags: tags}
+			c := NewCleaner(Config{
+				Client: client,
+				Filter: f,
+				Policy: policy.NewCountRetentionPolicy(1, tags),
+				Shared: tt.mode,
+				Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+			})
+
+			result, err := c.Clean(context.Background(), "repo")
+			if err != nil {
+				t.Fatalf("Clean() error = %v", err)
+			}
+			deleted := client.calls()
+			slices.Sort(deleted)
+			if !reflect.DeepEqual(deleted, tt.wantDeleted) {
+				t.Errorf("DeleteTag calls = %v, want %v", deleted, tt.wantDeleted)
+			}
+			if !reflect.DeepEqual(result.SharedTags, tt.wantShared) {
+				t.Errorf("SharedTags = %v, want %v", result.SharedTags, tt.wantShared)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package cleaner
+
+import (
+	"fmt"
+)
+
+// PartialFailureError is returned with a populated CleanResult when a run
+// completed but some operations (such as tag deletions) failed
+type PartialFailureError struct {
+	Errors []error
+}
+
+// Error implements the error interface
+func (e *PartialFailureError) Error() string {
+	if len(e.Errors) == 1 {
+		return fmt.Sprintf("completed with 1 failure: %v", e.Errors[0])
+	}
+	return fmt.Sprintf("completed with %d failures (first: %v)", len(e.Errors), e.Errors[0])
+}
+
+// Unwrap returns the individual failures for errors.Is and errors.As
+func (e *PartialFailureError) Unwrap() []error {
+	return e.Errors
+}
+
+// partialFailure returns a PartialFailureError for the result's errors, or
+// nil if there are none
+func partialFailure(result *CleanResult) error {
+	if len(result.Errors) == 0 {
+		return nil
+	}
+	return &PartialFailureError{Errors: result.Errors}
+}
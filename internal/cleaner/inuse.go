@@ -0,0 +1,118 @@
+package cleaner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/policy"
+)
+
+// InUseTimeout bounds how long fetching the in-use URL may take
+const InUseTimeout = 10 * time.Second
+
+// loadInUse collects the in-use tags of opts.Repository from the
+// configured file and URL (nil if neither is set)
+// Any failure is returned: running without the protection could delete a
+// live tag
+func loadInUse(ctx context.Context, opts Options) ([]string, error) {
+	var refs []string
+
+	if opts.InUseFile != "" {
+		lines, err := ReadTagList(opts.InUseFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read in-use file: %w", err)
+		}
+		refs = append(refs, lines...)
+	}
+
+	if opts.InUseURL != "" {
+		fetched, err := fetchList(ctx, opts.InUseURL, "tags")
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch in-use tags: %w", err)
+		}
+		refs = append(refs, fetched...)
+	}
+
+	if opts.InUseFile == "" && opts.InUseURL == "" {
+		return nil, nil
+	}
+
+	tags, err := TagsFor(refs, opts.Repository)
+	if err != nil {
+		return nil, fmt.Errorf("invalid in-use entry: %w", err)
+	}
+	return tags, nil
+}
+
+// loadPinned builds the digest-pinned protection from opts.PinnedDigests,
+// a file or an http(s) URL (nil if not set)
+// If the source cannot be read it fails closed: the returned policy keeps
+// every tag, together with the error for the caller to report
+func loadPinned(ctx context.Context, opts Options) (policy.RetentionPolicy, error) {
+	source := opts.PinnedDigests
+	if source == "" {
+		return nil, nil
+	}
+
+	var refs []string
+	var err error
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		refs, err = fetchList(ctx, source, "digests")
+	} else {
+		refs, err = ReadTagList(source)
+	}
+	if err != nil {
+		return policy.NewUnavailableDigestPolicy(), fmt.Errorf("failed to load pinned digests from %s: %w", source, err)
+	}
+
+	digests, err := DigestsFor(refs, opts.Repository)
+	if err != nil {
+		return policy.NewUnavailableDigestPolicy(), fmt.Errorf("invalid pinned digest in %s: %w", source, err)
+	}
+	return policy.NewDigestPinnedPolicy(digests), nil
+}
+
+// fetchList GETs a JSON list of strings, either an array or an object
+// with the list under key
+func fetchList(ctx context.Context, url, key string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, InUseTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []string
+	if err := json.Unmarshal(body, &list); err == nil {
+		return list, nil
+	}
+	var wrapped map[string]json.RawMessage
+	if err := json.Unmarshal(body, &wrapped); err == nil {
+		if err := json.Unmarshal(wrapped[key], &list); err == nil && list != nil {
+			return list, nil
+		}
+	}
+	return nil, fmt.Errorf("expected a JSON array of strings or an object with a %q array", key)
+}
@@ -0,0 +1,54 @@
+package cleaner
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// fetchLabels fills in the image labels of tags that pass the name-based
+// filters, fetching once per manifest with up to c.workers concurrent
+// requests. Tags whose labels cannot be fetched are left without labels,
+// so they never match a label selector.
+func (c *Cleaner) fetchLabels(ctx context.Context, repo string, tags []api.Tag) {
+	byManifest := make(map[string][]int)
+	for i, tag := range tags {
+		if c.filter != nil && !c.filter.Matches(tag.Name) {
+			continue
+		}
+		key := manifestKey(tag)
+		byManifest[key] = append(byManifest[key], i)
+	}
+
+	c.logger.Info("Fetching image labels", "manifests", len(byManifest))
+	limit := newAdaptiveLimit(c.workers, c.client.Throttled(), c.logger)
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	for _, indexes := range byManifest {
+		limit.acquire()
+		wg.Add(1)
+		go func(indexes []int) {
+			defer wg.Done()
+			name := tags[indexes[0]].Name
+			labels, err := c.client.GetImageLabels(ctx, repo, name)
+			limit.release(c.client.Throttled())
+			if err != nil {
+				c.logger.Warn("Failed to fetch image labels", "tag", name, "error", err)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, i := range indexes {
+				tags[i].Labels = labels
+			}
+		}(indexes)
+	}
+
+	wg.Wait()
+}
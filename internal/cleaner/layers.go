@@ -0,0 +1,70 @@
+package cleaner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// tagLayers fetches the layer sizes referenced by a tag, keyed by digest
+func (c *Cleaner) tagLayers(ctx context.Context, repo string, tag api.Tag) (map[string]int64, error) {
+	images, err := c.client.GetTagImages(ctx, repo, tag.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch layers of tag %s: %w", tag.Name, err)
+	}
+
+	layers := make(map[string]int64)
+	for _, image := range images {
+		for _, layer := range image.Layers {
+			if layer.Digest != "" {
+				layers[layer.Digest] = layer.Size
+			}
+		}
+	}
+	return layers, nil
+}
+
+// realisticSize estimates the bytes actually freed by deleting tagsToDelete:
+// the total size of layers referenced only by deleted tags, each counted once.
+// Layers shared with any surviving tag (including filtered-out ones) are excluded.
+// This costs one API request per tag in the repository.
+func (c *Cleaner) realisticSize(ctx context.Context, repo string, allTags, tagsToDelete []api.Tag) (int64, error) {
+	deleting := make(map[string]bool, len(tagsToDelete))
+	for _, tag := range tagsToDelete {
+		deleting[tag.Name] = true
+	}
+
+	kept := make(map[string]bool)
+	for _, tag := range allTags {
+		if deleting[tag.Name] {
+			continue
+		}
+		layers, err := c.tagLayers(ctx, repo, tag)
+		if err != nil {
+			return 0, err
+		}
+		for digest := range layers {
+			kept[digest] = true
+		}
+	}
+
+	freed := make(map[string]int64)
+	for _, tag := range tagsToDelete {
+		layers, err := c.tagLayers(ctx, repo, tag)
+		if err != nil {
+			return 0, err
+		}
+		for digest, size := range layers {
+			if !kept[digest] {
+				freed[digest] = size
+			}
+		}
+	}
+
+	var total int64
+	for _, size := range freed {
+		total += size
+	}
+	return total, nil
+}
@@ -0,0 +1,101 @@
+package cleaner
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+	"github.com/ataraskov/docker-hub-cleaner/internal/policy"
+)
+
+// Rule is a per-pattern retention rule from the config file: tags whose
+// name matches Pattern (and no earlier rule) are kept by its own days and
+// count policies, counted in its own sort order
+type Rule struct {
+	Pattern    string
+	KeepDays   int
+	KeepCount  int
+	SortMethod string // default: Options.SortMethod
+}
+
+// validateRules checks the per-pattern rules
+func (o *Options) validateRules() error {
+	for i, r := range o.Rules {
+		if r.Pattern == "" {
+			return fmt.Errorf("rule %d: pattern is required", i+1)
+		}
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return fmt.Errorf("rule %d: invalid pattern: %w", i+1, err)
+		}
+		if r.KeepDays < 0 || r.KeepCount < 0 {
+			return fmt.Errorf("rule %d (%s): keep-days and keep-count must not be negative", i+1, r.Pattern)
+		}
+		if r.KeepDays == 0 && r.KeepCount == 0 {
+			return fmt.Errorf("rule %d (%s): keep-days or keep-count is required", i+1, r.Pattern)
+		}
+		switch r.SortMethod {
+		case "", SortLexicographical, SortSemver, SortNumeric:
+		default:
+			return fmt.Errorf("rule %d (%s): invalid sort method: %s (must be 'lexicographical', 'semver' or 'numeric-dotted')",
+				i+1, r.Pattern, r.SortMethod)
+		}
+	}
+	return nil
+}
+
+// buildRules creates the retention policy of every rule from the tags it
+// governs, and returns the tags matching no rule (still in sort order)
+func buildRules(opts Options, sorted []api.Tag, loc *time.Location) ([]policy.Rule, []api.Tag, error) {
+	rules := make([]policy.Rule, len(opts.Rules))
+	for i, r := range opts.Rules {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, nil, fmt.Errorf("rule %d: invalid pattern: %w", i+1, err)
+		}
+		rules[i].Pattern = re
+	}
+
+	governed := make([][]api.Tag, len(rules))
+	var unmatched []api.Tag
+	for _, tag := range sorted {
+		if i := policy.MatchRule(rules, tag.Name); i >= 0 {
+			governed[i] = append(governed[i], tag)
+		} else {
+			unmatched = append(unmatched, tag)
+		}
+	}
+
+	for i, r := range opts.Rules {
+		tags := governed[i]
+		method := r.SortMethod
+		if method == "" {
+			method = opts.SortMethod
+		}
+		if method != opts.SortMethod {
+			ruleOpts := opts
+			ruleOpts.SortMethod = method
+			ruleOpts.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+			sorter, err := buildSorter(ruleOpts)
+			if err != nil {
+				return nil, nil, fmt.Errorf("rule %d (%s): %w", i+1, r.Pattern, err)
+			}
+			tags = sorter.Sort(tags)
+		}
+
+		switch {
+		case r.KeepDays > 0 && r.KeepCount > 0:
+			rules[i].Policy = policy.NewHybridRetentionPolicy(r.KeepCount, r.KeepDays, opts.AgeField, loc, tags)
+		case r.KeepDays > 0:
+			rules[i].Policy = policy.NewDaysRetentionPolicy(r.KeepDays, opts.AgeField).WithTimezone(loc)
+		default:
+			rules[i].Policy = policy.NewCountRetentionPolicy(r.KeepCount, tags)
+		}
+		opts.Logger.Info("Retention rule enabled", "pattern", r.Pattern, "tags", len(tags),
+			"days", r.KeepDays, "count", r.KeepCount, "sort_method", method)
+	}
+
+	return rules, unmatched, nil
+}
@@ -0,0 +1,110 @@
+package cleaner
+
+import (
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// rulesKept builds the policy for opts over tags (already in sort order)
+// and returns the names of the tags it keeps
+func rulesKept(t *testing.T, opts Options, tags []api.Tag) []string {
+	t.Helper()
+	opts.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	opts.setDefaults()
+	p, err := buildPolicy(opts, tags, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kept(p, tags)
+}
+
+func TestRulesFirstMatchWins(t *testing.T) {
+	days := map[string]int{"feature-c": 1, "feature-b": 2, "feature-a": 300}
+	tags := agedTags(days, "feature-c", "feature-b", "feature-a")
+
+	// feature-a also matches the second rule, whose count would delete it
+	opts := Options{Rules: []Rule{
+		{Pattern: "^feature-a$", KeepDays: 1000},
+		{Pattern: "^feature-", KeepCount: 1},
+	}}
+	want := []string{"feature-c", "feature-a"}
+	if got := rulesKept(t, opts, tags); !reflect.DeepEqual(got, want) {
+		t.Errorf("kept = %v, want %v", got, want)
+	}
+
+	// In the other order the general rule governs every tag
+	opts.Rules[0], opts.Rules[1] = opts.Rules[1], opts.Rules[0]
+	want = []string{"feature-c"}
+	if got := rulesKept(t, opts, tags); !reflect.DeepEqual(got, want) {
+		t.Errorf("reordered: kept = %v, want %v", got, want)
+	}
+}
+
+func TestRulesCountScope(t *testing.T) {
+	order := []string{"main-3", "feature-c", "main-2", "feature-b", "main-1", "feature-a"}
+	days := map[string]int{"main-3": 1, "feature-c": 2, "main-2": 3, "feature-b": 4, "main-1": 5, "feature-a": 6}
+	tags := agedTags(days, order...)
+
+	// The rule counts only its own tags, the fallback only the others
+	opts := Options{
+		KeepCount: 1,
+		Rules:     []Rule{{Pattern: "^feature-", KeepCount: 2}},
+	}
+	want := []string{"main-3", "feature-c", "feature-b"}
+	if got := rulesKept(t, opts, tags); !reflect.DeepEqual(got, want) {
+		t.Errorf("kept = %v, want %v", got, want)
+	}
+}
+
+func TestRulesSortMethod(t *testing.T) {
+	// Lexicographical order, newest first; semver puts 1.10.0 ahead
+	days := map[string]int{"1.9.0": 1, "1.2.0": 1, "1.10.0": 1}
+	tags := agedTags(days, "1.9.0", "1.2.0", "1.10.0")
+
+	for _, tt := range []struct {
+		method string
+		want   []string
+	}{
+		{"", []string{"1.9.0"}},
+		{SortSemver, []string{"1.10.0"}},
+	} {
+		t.Run("sort "+tt.method, func(t *testing.T) {
+			opts := Options{
+				SortMethod: SortLexicographical,
+				Rules:      []Rule{{Pattern: `^\d`, KeepCount: 1, SortMethod: tt.method}},
+			}
+			if got := rulesKept(t, opts, tags); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("kept = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRulesFallback(t *testing.T) {
+	days := map[string]int{"pr-2": 1, "main": 50, "pr-1": 60, "nightly": 70}
+	tags := agedTags(days, "pr-2", "main", "pr-1", "nightly")
+	rules := []Rule{{Pattern: "^pr-", KeepDays: 30}}
+
+	for _, tt := range []struct {
+		name     string
+		keepDays int
+		want     []string
+	}{
+		// Unmatched tags follow the command-line policies
+		{"fallback", 40, []string{"pr-2"}},
+		{"fallback keeps", 55, []string{"pr-2", "main"}},
+		// Without command-line policies unmatched tags are kept
+		{"no fallback", 0, []string{"pr-2", "main", "nightly"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{KeepDays: tt.keepDays, Rules: rules}
+			if got := rulesKept(t, opts, tags); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("kept = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
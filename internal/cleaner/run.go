@@ -0,0 +1,1289 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+	"github.com/ataraskov/docker-hub-cleaner/internal/filter"
+	"github.com/ataraskov/docker-hub-cleaner/internal/policy"
+	"github.com/ataraskov/docker-hub-cleaner/internal/snapshot"
+	sortpkg "github.com/ataraskov/docker-hub-cleaner/internal/sort"
+	"github.com/ataraskov/docker-hub-cleaner/internal/state"
+)
+
+// Sort methods
+const (
+	SortLexicographical = "lexicographical"
+	SortSemver          = "semver"
+	SortDate            = "date" // only as a fallback for non-semver tags
+	SortName            = "name" // alias of SortLexicographical for the fallback
+	SortNumeric         = "numeric-dotted"
+)
+
+// Count units
+const (
+	CountUnitTags      = "tags"
+	CountUnitManifests = "manifests"
+)
+
+// Policy modes
+const (
+	PolicyModeOR        = "or"         // keep a tag if any retention policy keeps it
+	PolicyModeAND       = "and"        // keep a tag only if every retention policy keeps it
+	PolicyModeSizeFloor = "size-floor" // KeepCount is a floor, older tags are kept within KeepSize
+)
+
+// Registries
+const (
+	RegistryDockerHub = "dockerhub"
+	RegistryGHCR      = "ghcr" // GitHub Container Registry, through the GitHub packages API
+	RegistryOCI       = "oci"  // any registry speaking the OCI distribution API, at RegistryURL
+)
+
+// Delete priorities select which candidates are deleted first under MaxDelete
+const (
+	PriorityAge  = "age"  // oldest first
+	PrioritySize = "size" // largest first
+)
+
+// Options configures a complete cleaning run (mirrors the CLI flags)
+type Options struct {
+	// Authentication
+	Client        api.Registry      // optional: shared authenticated client (see Connect)
+	Authenticator api.Authenticator // optional: takes precedence over the fields below
+	Username      string
+	Password      string
+	Token         string
+	TokenType     api.TokenType // default: api.TokenTypeJWT
+	Repository    string
+	Registry      string // RegistryDockerHub (default), RegistryGHCR or RegistryOCI
+	RegistryURL   string // RegistryOCI: registry base URL, e.g. https://registry.example.com
+
+	// Retention policy
+	KeepDays         int
+	KeepCount        int
+	KeepSize         int64        // size-floor mode: byte budget for the kept tags (the KeepCount newest are kept regardless)
+	MaxSize          int64        // keep the newest tags while their combined size fits this byte budget (0 = off)
+	PolicyMode       string       // PolicyModeOR (default), PolicyModeAND or PolicyModeSizeFloor
+	KeepPulledWithin int          // keep tags pulled within X days
+	KeepNeverPulled  bool         // with KeepPulledWithin: keep tags without a last-pulled time
+	AgeField         api.AgeField // default: api.AgeFieldLastUpdated
+	Timezone         string       // optional: count KeepDays from midnight in this IANA zone
+	SortMethod       string       // SortLexicographical (default), SortSemver or SortNumeric
+	FallbackSort     string       // semver only: SortLexicographical (default), SortDate or SortNumeric for non-semver tags
+	SemverTiebreak   string       // semver only: "date" (default) or "name" for equal-precedence tags
+	PrereleaseFirst  bool         // semver only: order prereleases ahead of their release
+	CountUnit        string       // CountUnitTags (default) or CountUnitManifests
+	Protect          []string     // tag names or regexes that are never deleted, whatever the other policies
+	GroupBy          string
+	KeepPattern      string
+	LatestPerGroup   string // keep only the newest tag per group (first capture); non-matching tags are not candidates
+	DeletePattern    string // delete matching tags directly (instead of KeepDays/KeepCount)
+	InUseFile        string // optional: file listing deployed tags, always kept
+	InUseURL         string // optional: URL returning deployed tags as JSON, always kept
+	PinnedDigests    string // optional: file or URL listing digests pinned by consumers, always kept
+	MaxDelete        int    // delete at most this many tags per run, deferring the rest (0 = no cap)
+	DeletePriority   string // with MaxDelete: PriorityAge (default) or PrioritySize
+	PrunePrereleases bool
+	KeepPrereleases  bool   // always keep semver prerelease tags
+	PruneReleased    int    // delete prereleases whose stable release is older than X days (0 = off)
+	KeepHighest      bool   // always keep the highest stable semver tag
+	KeepPerMajor     int    // with semver sorting: keep the newest X tags of each major series
+	KeepPerMinor     int    // with semver sorting: keep the newest X tags of each major.minor series
+	Rules            []Rule // per-pattern rules; the first matching rule decides, other tags follow the policies above
+
+	// Filtering
+	TagPattern     string
+	ExcludePattern string
+	HasArch        string
+	LacksArch      string
+	VersionRange   string
+	RangeInvalid   bool // with VersionRange: include tags that are not valid semver
+	LabelSelector  string
+	MinAge         time.Duration // tags younger than this are never candidates (0 = off)
+	TagLengthMin   int
+	TagLengthMax   int
+	TagCharset     string
+	Status         string // filter.StatusActive, filter.StatusInactive or filter.StatusAll (default)
+	StripPrefix    string
+	TagNormalize   string // "pattern=>replacement" view of tag names for sorting/grouping
+	ExplainSort    bool
+
+	// Execution
+	DryRun        bool
+	Verbose       bool
+	Concurrency   int
+	SharedDigests string
+	ByManifest    bool
+	RealisticSize bool
+	PruneUntagged bool
+	FailFast      bool
+	DeleteTimeout time.Duration
+	Verify        bool
+	StateFile     string
+	SnapshotDir   string
+	TagLimit      int
+	PageSize      int // default: api.DefaultPageSize
+	MaxRetries    int // retries for a failed tag page fetch (0 = none)
+	ShowLargest   int
+	ShowRemaining bool
+	ShowRepoInfo  bool // tolerate a 403 on the repository metadata (only the header needs it)
+	SimulateTime  bool
+	MinRemaining  int  // abort if fewer tags would remain (0 = default 1; set AllowEmpty for no floor)
+	AllowEmpty    bool // disable the MinRemaining floor
+	MinKeep       int  // never delete the newest N filtered tags in sort order (0 = off)
+	AllowLatest   bool // allow deleting tags pointing at the image of the latest tag
+
+	Logger   *slog.Logger
+	OnAction func(TagAction) // optional: receives each tag action as it happens
+	Confirm  ConfirmFunc     // optional: approves deletions before they happen
+}
+
+// setDefaults fills in zero-valued options
+func (o *Options) setDefaults() {
+	if o.Registry == "" {
+		o.Registry = RegistryDockerHub
+	}
+	if o.TokenType == "" {
+		o.TokenType = api.TokenTypeJWT
+	}
+	if o.AgeField == "" {
+		o.AgeField = api.AgeFieldLastUpdated
+	}
+	if o.Status == "" {
+		o.Status = filter.StatusAll
+	}
+	if o.SortMethod == "" {
+		o.SortMethod = SortLexicographical
+	}
+	if o.FallbackSort == SortName {
+		o.FallbackSort = SortLexicographical
+	}
+	if o.CountUnit == "" {
+		o.CountUnit = CountUnitTags
+	}
+	if o.PolicyMode == "" {
+		o.PolicyMode = PolicyModeOR
+	}
+	if o.DeletePriority == "" {
+		o.DeletePriority = PriorityAge
+	}
+	if o.Concurrency == 0 {
+		o.Concurrency = api.DefaultRateBurst
+	}
+	if o.PageSize == 0 {
+		o.PageSize = api.DefaultPageSize
+	}
+	if o.MinRemaining == 0 {
+		o.MinRemaining = 1
+	}
+	if o.AllowEmpty {
+		o.MinRemaining = 0
+	}
+	if o.Logger == nil {
+		o.Logger = slog.Default()
+	}
+}
+
+// authenticator returns the configured Authenticator, or one built from the
+// token or username/password fields (nil if no credentials are set)
+func (o *Options) authenticator() api.Authenticator {
+	switch {
+	case o.Authenticator != nil:
+		return o.Authenticator
+	case o.Token != "":
+		return api.NewTokenAuth(o.Token, o.TokenType)
+	case o.Username != "" && o.Password != "":
+		return api.NewPasswordAuth(o.Username, o.Password)
+	}
+	return nil
+}
+
+// missingCredentials reports whether no client or credentials are set
+// where the registry needs them (OCI registries may allow anonymous access)
+func (o *Options) missingCredentials() bool {
+	return o.Client == nil && o.authenticator() == nil && o.Registry != RegistryOCI
+}
+
+// validate checks options for consistency
+func (o *Options) validate() error {
+	if o.missingCredentials() {
+		return fmt.Errorf("either --token or --username/--password must be provided")
+	}
+
+	if o.Repository == "" {
+		return fmt.Errorf("--repository is required")
+	}
+
+	if err := o.validateRules(); err != nil {
+		return err
+	}
+
+	if err := o.validateRegistry(); err != nil {
+		return err
+	}
+
+	switch o.SharedDigests {
+	case SharedDigestsOff, SharedDigestsWarn, SharedDigestsSkip:
+	default:
+		return fmt.Errorf("invalid protect-shared-digests mode: %s (must be 'warn' or 'skip')", o.SharedDigests)
+	}
+
+	if o.Concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+
+	if o.DeleteTimeout < 0 {
+		return fmt.Errorf("--delete-timeout must not be negative")
+	}
+
+	if o.MaxRetries < 0 {
+		return fmt.Errorf("--max-retries must not be negative")
+	}
+
+	if o.PageSize < 1 || o.PageSize > api.MaxPageSize {
+		return fmt.Errorf("--page-size must be between 1 and %d", api.MaxPageSize)
+	}
+
+	if o.MinRemaining < 0 {
+		return fmt.Errorf("--min-remaining must not be negative")
+	}
+
+	if o.MinKeep < 0 {
+		return fmt.Errorf("--min-keep must not be negative")
+	}
+
+	if o.MinAge < 0 {
+		return fmt.Errorf("--min-age must not be negative")
+	}
+
+	if o.TagLimit < 0 {
+		return fmt.Errorf("--tag-limit must not be negative")
+	}
+
+	if o.MaxDelete < 0 {
+		return fmt.Errorf("--max-delete must not be negative")
+	}
+
+	switch o.DeletePriority {
+	case PriorityAge, PrioritySize:
+	default:
+		return fmt.Errorf("invalid delete priority: %s (must be 'age' or 'size')", o.DeletePriority)
+	}
+
+	switch o.AgeField {
+	case api.AgeFieldLastUpdated, api.AgeFieldLastPushed:
+	default:
+		return fmt.Errorf("invalid age field: %s (must be 'last_updated' or 'last_pushed')", o.AgeField)
+	}
+
+	if _, err := location(o.Timezone); err != nil {
+		return err
+	}
+
+	switch o.FallbackSort {
+	case "", SortLexicographical:
+	case SortDate, SortNumeric:
+		if o.SortMethod != SortSemver {
+			return fmt.Errorf("--fallback-sort requires --sort-method semver")
+		}
+	default:
+		return fmt.Errorf("invalid fallback sort: %s (must be 'lexicographical', 'name', 'date' or 'numeric-dotted')", o.FallbackSort)
+	}
+
+	switch sortpkg.Tiebreak(o.SemverTiebreak) {
+	case "", sortpkg.TiebreakDate, sortpkg.TiebreakName:
+	default:
+		return fmt.Errorf("invalid semver tiebreak: %s (must be 'date' or 'name')", o.SemverTiebreak)
+	}
+
+	if o.PrereleaseFirst && o.SortMethod != SortSemver {
+		return fmt.Errorf("--prerelease-first requires --sort-method semver")
+	}
+
+	if o.KeepPerMajor < 0 || o.KeepPerMinor < 0 {
+		return fmt.Errorf("--keep-per-major and --keep-per-minor must not be negative")
+	}
+
+	if (o.KeepPerMajor > 0 || o.KeepPerMinor > 0) && o.SortMethod != SortSemver {
+		return fmt.Errorf("--keep-per-major and --keep-per-minor require --sort-method semver")
+	}
+
+	switch o.CountUnit {
+	case CountUnitTags, CountUnitManifests:
+	default:
+		return fmt.Errorf("invalid count unit: %s (must be 'tags' or 'manifests')", o.CountUnit)
+	}
+
+	if o.CountUnit == CountUnitManifests && o.GroupBy != "" {
+		return fmt.Errorf("--count-unit manifests cannot be combined with --group-by")
+	}
+
+	switch o.PolicyMode {
+	case PolicyModeOR, PolicyModeAND:
+		if o.KeepSize != 0 {
+			return fmt.Errorf("--keep-size requires --policy-mode size-floor")
+		}
+	case PolicyModeSizeFloor:
+		if o.KeepCount == 0 || o.KeepSize <= 0 {
+			return fmt.Errorf("--policy-mode size-floor requires --keep-count and --keep-size")
+		}
+		if o.GroupBy != "" || o.CountUnit == CountUnitManifests {
+			return fmt.Errorf("--policy-mode size-floor cannot be combined with --group-by or --count-unit manifests")
+		}
+	default:
+		return fmt.Errorf("invalid policy mode: %s (must be 'or', 'and' or 'size-floor')", o.PolicyMode)
+	}
+
+	if o.MaxSize < 0 {
+		return fmt.Errorf("--max-size must not be negative")
+	}
+
+	if o.MaxSize > 0 && o.PolicyMode == PolicyModeSizeFloor {
+		return fmt.Errorf("--max-size cannot be combined with --policy-mode size-floor (use --keep-size)")
+	}
+
+	if o.GroupBy != "" && o.KeepCount == 0 {
+		return fmt.Errorf("--group-by requires --keep-count")
+	}
+
+	if _, err := regexp.Compile(o.GroupBy); err != nil {
+		return fmt.Errorf("invalid group-by pattern: %w", err)
+	}
+
+	if _, err := regexp.Compile(o.KeepPattern); err != nil {
+		return fmt.Errorf("invalid keep pattern: %w", err)
+	}
+
+	if _, err := policy.NewProtectPolicy(o.Protect); err != nil {
+		return fmt.Errorf("invalid protect entry: %w", err)
+	}
+
+	if _, err := regexp.Compile(o.LatestPerGroup); err != nil {
+		return fmt.Errorf("invalid keep-latest-per-group pattern: %w", err)
+	}
+
+	if o.LatestPerGroup != "" && (o.GroupBy != "" || o.DeletePattern != "") {
+		return fmt.Errorf("--keep-latest-per-group cannot be combined with --group-by or --delete-pattern")
+	}
+
+	if _, err := regexp.Compile(o.DeletePattern); err != nil {
+		return fmt.Errorf("invalid delete pattern: %w", err)
+	}
+
+	if o.DeletePattern != "" && (o.KeepDays > 0 || o.KeepCount > 0 || o.MaxSize > 0 || o.KeepPulledWithin > 0 || o.PrunePrereleases ||
+		o.PruneReleased > 0 || o.KeepPerMajor > 0 || o.KeepPerMinor > 0) {
+		return fmt.Errorf("--delete-pattern cannot be combined with --keep-days, --keep-count, --max-size, --keep-pulled-within, --keep-per-major, --keep-per-minor, --prune-prereleases or --prune-released-prereleases")
+	}
+
+	if o.PruneReleased < 0 {
+		return fmt.Errorf("--prune-released-prereleases must not be negative")
+	}
+
+	if o.PruneReleased > 0 && o.PrunePrereleases {
+		return fmt.Errorf("--prune-released-prereleases cannot be combined with --prune-prereleases (which deletes every prerelease)")
+	}
+
+	if o.RangeInvalid && o.VersionRange == "" {
+		return fmt.Errorf("--version-range-include-invalid requires --version-range")
+	}
+
+	if o.KeepPrereleases && (o.PrunePrereleases || o.PruneReleased > 0) {
+		return fmt.Errorf("--exclude-semver-prerelease cannot be combined with --prune-prereleases or --prune-released-prereleases")
+	}
+
+	if o.KeepPulledWithin < 0 {
+		return fmt.Errorf("--keep-pulled-within must not be negative")
+	}
+
+	if o.KeepNeverPulled && o.KeepPulledWithin == 0 {
+		return fmt.Errorf("--keep-never-pulled requires --keep-pulled-within")
+	}
+
+	if _, err := regexp.Compile(o.StripPrefix); err != nil {
+		return fmt.Errorf("invalid strip-prefix pattern: %w", err)
+	}
+
+	if _, err := normalizer(*o); err != nil {
+		return err
+	}
+
+	if o.KeepDays == 0 && o.KeepCount == 0 && o.MaxSize == 0 && o.KeepPulledWithin == 0 && o.LatestPerGroup == "" &&
+		o.KeepPerMajor == 0 && o.KeepPerMinor == 0 && o.DeletePattern == "" && len(o.Rules) == 0 {
+		return fmt.Errorf("at least one retention policy (--keep-days, --keep-count, --max-size, --keep-pulled-within, --keep-per-major, --keep-per-minor or --keep-latest-per-group), --delete-pattern or a config file rule must be specified")
+	}
+
+	return nil
+}
+
+// validateRegistry checks that the options only use features the registry
+// backend supports (GHCR reports no sizes, platforms, pulls, labels or status)
+func (o *Options) validateRegistry() error {
+	if o.RegistryURL != "" && o.Registry != RegistryOCI {
+		return fmt.Errorf("--registry-url requires --registry oci")
+	}
+
+	var unsupported map[string]bool
+	switch o.Registry {
+	case RegistryDockerHub:
+		return nil
+	case RegistryGHCR:
+		if _, ok := o.authenticator().(*api.TokenAuth); o.Client == nil && !ok {
+			return fmt.Errorf("--registry ghcr requires a GitHub token (--token)")
+		}
+		unsupported = map[string]bool{
+			"--has-arch/--lacks-arch":  o.HasArch != "" || o.LacksArch != "",
+			"--label-selector":         o.LabelSelector != "",
+			"--keep-pulled-within":     o.KeepPulledWithin > 0,
+			"--status":                 o.Status != filter.StatusAll,
+			"--policy-mode size-floor": o.PolicyMode == PolicyModeSizeFloor,
+			"--max-size":               o.MaxSize > 0,
+			"--delete-priority size":   o.DeletePriority == PrioritySize,
+			"--realistic-size":         o.RealisticSize,
+			"--prune-untagged":         o.PruneUntagged,
+		}
+	case RegistryOCI:
+		if u, err := url.Parse(o.RegistryURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("--registry oci requires --registry-url with the registry's http(s) base URL")
+		}
+		unsupported = map[string]bool{
+			"--keep-pulled-within": o.KeepPulledWithin > 0,
+			"--status":             o.Status != filter.StatusAll,
+			"--realistic-size":     o.RealisticSize,
+			"--prune-untagged":     o.PruneUntagged,
+			"--tag-limit":          o.TagLimit > 0, // deletion needs every tag to see which share a manifest
+		}
+	default:
+		return fmt.Errorf("invalid registry: %s (must be 'dockerhub', 'ghcr' or 'oci')", o.Registry)
+	}
+
+	var flags []string
+	for flag, set := range unsupported {
+		if set {
+			flags = append(flags, flag)
+		}
+	}
+	if len(flags) > 0 {
+		sort.Strings(flags)
+		return fmt.Errorf("--registry %s does not support %s", o.Registry, strings.Join(flags, ", "))
+	}
+
+	return nil
+}
+
+// Run performs a complete cleaning run: authentication, filter, sorter and
+// policy wiring, and deletion of tags (and untagged manifests if enabled)
+// If the run completed with failures, the result is returned with a
+// *PartialFailureError
+func Run(ctx context.Context, opts Options) (*CleanResult, error) {
+	opts.setDefaults()
+	// Count this repository's requests apart from others sharing the client
+	ctx, apiStats := api.WithStats(ctx)
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	logger := opts.Logger
+
+	client, info, err := connect(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	tagFilter, err := buildFilter(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	inUse, err := loadInUse(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	pinned, pinnedErr := loadPinned(ctx, opts)
+	if pinnedErr != nil {
+		// Fail closed: without the list any deletion could break a pinned consumer
+		logger.Warn("Pinned digests unavailable; no tags will be deleted", "error", pinnedErr)
+	}
+
+	sorter, err := buildSorter(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.TagLimit > 0 {
+		logger.Warn("Tag limit enabled; sorting and retention only see the fetched subset", "limit", opts.TagLimit)
+	}
+
+	// Open state file for resumable deletion
+	var st *state.State
+	if opts.StateFile != "" && !opts.DryRun {
+		st, err = state.Open(opts.StateFile)
+		if err != nil {
+			return nil, err
+		}
+		defer st.Close()
+		logger.Info("State file enabled", "path", opts.StateFile, "recorded", st.Len())
+	}
+
+	c := NewCleaner(Config{
+		Client:        client,
+		Filter:        tagFilter,
+		Sorter:        sorter,
+		DryRun:        opts.DryRun,
+		Logger:        logger,
+		Verbose:       opts.Verbose,
+		TagLimit:      opts.TagLimit,
+		Shared:        opts.SharedDigests,
+		State:         st,
+		ShowLargest:   opts.ShowLargest,
+		ShowRemaining: opts.ShowRemaining,
+		SimulateTime:  opts.SimulateTime,
+		FetchLabels:   opts.LabelSelector != "",
+		AllowLatest:   opts.AllowLatest,
+		PruneUntagged: opts.PruneUntagged,
+		FailFast:      opts.FailFast,
+		DeleteTimeout: opts.DeleteTimeout,
+		OnAction:      opts.OnAction,
+		Confirm:       opts.Confirm,
+		ExplainSort:   opts.ExplainSort,
+		ByManifest:    opts.ByManifest,
+		RealisticSize: opts.RealisticSize,
+		MinRemaining:  opts.MinRemaining,
+		MinKeep:       opts.MinKeep,
+		Concurrency:   opts.Concurrency,
+		MaxDelete:     opts.MaxDelete,
+		Priority:      opts.DeletePriority,
+		AgeField:      opts.AgeField,
+		Pinned:        pinned,
+		BuildPolicy: func(sorted []api.Tag) (policy.RetentionPolicy, error) {
+			return buildPolicy(opts, sorted, inUse, pinned)
+		},
+	})
+
+	if opts.DryRun {
+		logger.Info("=== DRY RUN MODE - No tags will be deleted ===")
+	}
+
+	// A partial failure still completes the run; it is returned at the end
+	result, err := c.Clean(ctx, opts.Repository)
+	var partial *PartialFailureError
+	if err != nil && !errors.As(err, &partial) {
+		return nil, fmt.Errorf("cleaning failed: %w", err)
+	}
+	result.RepoInfo = info
+
+	if opts.Verify {
+		if err := c.Verify(ctx, opts.Repository, result); err != nil {
+			logger.Error("Deletion verification failed", "error", err)
+			result.Errors = append(result.Errors, err)
+		}
+	}
+
+	if opts.PruneUntagged && pinnedErr != nil {
+		logger.Warn("Skipping untagged manifest cleanup: pinned digests unavailable")
+	} else if opts.PruneUntagged {
+		if err := c.CleanUntagged(ctx, opts.Repository, result); err != nil {
+			logger.Error("Untagged manifest cleanup failed", "error", err)
+			result.Errors = append(result.Errors, err)
+		}
+	}
+
+	if opts.SnapshotDir != "" {
+		recordSnapshot(opts, result)
+	}
+
+	result.APIStats = apiStats()
+	return result, partialFailure(result)
+}
+
+// ErrNotConfirmed is returned when the Confirm callback declines the
+// deletion of a repository
+var ErrNotConfirmed = errors.New("repository deletion not confirmed")
+
+// DeleteRepository removes a whole repository, ignoring retention policies
+// and filters. The tags it holds are listed (at debug level) first and
+// passed to the Confirm callback, if set, which must approve all of them; in
+// dry-run mode nothing is deleted. Returns the number of tags in the repository.
+func DeleteRepository(ctx context.Context, opts Options) (int, error) {
+	opts.setDefaults()
+	if opts.missingCredentials() {
+		return 0, fmt.Errorf("either --token or --username/--password must be provided")
+	}
+	if opts.Repository == "" {
+		return 0, fmt.Errorf("--repository is required")
+	}
+
+	logger := opts.Logger
+
+	client, _, err := connect(ctx, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	tags, err := client.ListTags(ctx, opts.Repository)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list tags: %w", err)
+	}
+	for _, tag := range tags {
+		logger.Debug("  Tag", "tag", tag.Name, "updated", tag.LastUpdated, "size", FormatSize(tag.FullSize))
+	}
+
+	if opts.DryRun {
+		logger.Warn("DRY RUN: Would delete repository", "repository", opts.Repository, "tags", len(tags))
+		return len(tags), nil
+	}
+
+	if opts.Confirm != nil {
+		approved, err := opts.Confirm(opts.Repository, tags)
+		if err != nil {
+			return len(tags), fmt.Errorf("%w: %w", ErrNotConfirmed, err)
+		}
+		if len(approved) != len(tags) {
+			return len(tags), ErrNotConfirmed
+		}
+	}
+
+	logger.Warn("Deleting repository", "repository", opts.Repository, "tags", len(tags))
+	if err := client.DeleteRepository(ctx, opts.Repository); err != nil {
+		return len(tags), fmt.Errorf("failed to delete repository %s: %w", opts.Repository, err)
+	}
+	logger.Info("Deleted repository", "repository", opts.Repository)
+
+	return len(tags), nil
+}
+
+// recordSnapshot diffs the fetched tags against the previous snapshot and
+// writes a new one. Failures are logged but never fail the run.
+func recordSnapshot(opts Options, result *CleanResult) {
+	logger := opts.Logger
+	cur := snapshot.New(opts.Repository, result.Fetched)
+
+	prev, err := snapshot.Latest(opts.SnapshotDir, opts.Repository)
+	if err != nil {
+		logger.Warn("Failed to load previous snapshot", "error", err)
+	} else if prev != nil {
+		result.Changes = snapshot.Compare(prev, cur)
+		logger.Info("Changes since last snapshot", "since", prev.Taken,
+			"added", len(result.Changes.Added), "removed", len(result.Changes.Removed))
+	}
+
+	path, err := snapshot.Write(opts.SnapshotDir, cur)
+	if err != nil {
+		logger.Warn("Failed to write snapshot", "error", err)
+		return
+	}
+	logger.Debug("Wrote snapshot", "path", path)
+}
+
+// Connect creates an authenticated client that several runs can share via
+// Options.Client, so one rate limiter paces all of them
+func Connect(ctx context.Context, opts Options) (api.Registry, error) {
+	opts.setDefaults()
+	if opts.missingCredentials() {
+		return nil, fmt.Errorf("either --token or --username/--password must be provided")
+	}
+	return authenticate(ctx, opts)
+}
+
+// ListRepositories returns the full names of repositories in namespace
+// whose name matches pattern (all repositories if pattern is empty)
+func ListRepositories(ctx context.Context, opts Options, namespace, pattern string) ([]string, error) {
+	opts.setDefaults()
+	if opts.missingCredentials() {
+		return nil, fmt.Errorf("either --token or --username/--password must be provided")
+	}
+
+	var repoFilter filter.TagFilter
+	if pattern != "" {
+		f, err := filter.NewRegexFilter(pattern, false)
+		if err != nil {
+			return nil, fmt.Errorf("invalid repository regex: %w", err)
+		}
+		repoFilter = f
+	}
+
+	client, err := authenticate(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	repos, err := client.ListRepositories(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories in %s: %w", namespace, err)
+	}
+
+	var names []string
+	for _, repo := range repos {
+		if repoFilter != nil && !repoFilter.Matches(repo.Name) {
+			opts.Logger.Debug("Skipping repository", "repository", repo.Name)
+			continue
+		}
+		names = append(names, namespace+"/"+repo.Name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// authenticate creates a client and applies the configured credentials
+// (or returns the shared client, if set)
+func authenticate(ctx context.Context, opts Options) (api.Registry, error) {
+	if opts.Client != nil {
+		return opts.Client, nil
+	}
+
+	clientOpts := []api.Option{api.WithPageSize(opts.PageSize), api.WithMaxRetries(opts.MaxRetries), api.WithLogger(opts.Logger)}
+	auth := opts.authenticator()
+
+	switch opts.Registry {
+	case RegistryGHCR:
+		client := api.NewGHCRClient(clientOpts...)
+		if err := client.Authenticate(ctx, auth); err != nil {
+			return nil, fmt.Errorf("authentication failed: %w", err)
+		}
+		opts.Logger.Info("Authenticated", "registry", opts.Registry, "method", "token")
+		return client, nil
+	case RegistryOCI:
+		client := api.NewOCIClient(opts.RegistryURL, clientOpts...)
+		if err := client.Authenticate(ctx, auth); err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w", opts.RegistryURL, err)
+		}
+		method := "anonymous"
+		if auth != nil {
+			method = auth.Name()
+		}
+		opts.Logger.Info("Connected", "registry", opts.RegistryURL, "method", method)
+		return client, nil
+	}
+
+	client := api.NewClient(clientOpts...)
+	if err := auth.Authenticate(ctx, client); err != nil {
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+	opts.Logger.Info("Authenticated", "method", auth.Name())
+
+	return client, nil
+}
+
+// connect creates an authenticated client, checks repository access and
+// returns the repository metadata (nil if the API refuses to share it)
+func connect(ctx context.Context, opts Options) (api.Registry, *api.Repository, error) {
+	logger := opts.Logger
+
+	client, err := authenticate(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The client's rate limiter is shared by all workers and is the real throttle
+	if maxWorkers := client.MaxUsefulConcurrency(); opts.Concurrency > maxWorkers {
+		logger.Warn("Concurrency exceeds what the API rate limit can sustain; extra workers will wait on the shared limiter",
+			"concurrency", opts.Concurrency, "max_useful", maxWorkers)
+	}
+
+	// Pre-flight: check repository access before the expensive tag listing
+	// (Docker Hub only; other registries report a missing repository when listing tags)
+	hub, ok := client.(*api.Client)
+	if !ok {
+		return client, nil, nil
+	}
+	tokenAuth, isToken := opts.authenticator().(*api.TokenAuth)
+	repo, err := hub.GetRepository(ctx, opts.Repository)
+	var apiErr *api.APIError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden && opts.ShowRepoInfo:
+		// The metadata was only wanted for the header; cleaning can still work
+		logger.Warn("Repository metadata unavailable; skipping pre-flight permission check", "repository", opts.Repository)
+		return client, nil, nil
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden:
+		return nil, nil, fmt.Errorf("not permitted to access repository %s: %w", opts.Repository, err)
+	case errors.Is(err, api.ErrNotFound):
+		return nil, nil, fmt.Errorf("repository %s not found (check the --repository name): %w", opts.Repository, err)
+	case errors.Is(err, api.ErrUnauthorized) && isToken:
+		return nil, nil, fmt.Errorf("token rejected by Docker Hub (check --token-type, currently %q): %w", tokenAuth.TokenType(), err)
+	case errors.Is(err, api.ErrUnauthorized):
+		return nil, nil, fmt.Errorf("not authorized to access repository %s: %w", opts.Repository, err)
+	case err != nil:
+		return nil, nil, fmt.Errorf("failed to check repository access: %w", err)
+	}
+	logger.Debug("Repository access confirmed", "repository", opts.Repository)
+
+	if !opts.DryRun && !repo.Permissions.Write && !repo.Permissions.Admin {
+		logger.Warn("Credentials may lack permission to delete tags", "repository", opts.Repository,
+			"read", repo.Permissions.Read, "write", repo.Permissions.Write, "admin", repo.Permissions.Admin)
+	}
+
+	return client, repo, nil
+}
+
+// buildFilter creates the tag filter from options (nil if no filtering)
+func buildFilter(opts Options) (filter.TagFilter, error) {
+	logger := opts.Logger
+	var filters []filter.TagFilter
+
+	if opts.TagPattern != "" {
+		f, err := filter.NewRegexFilter(opts.TagPattern, false)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag pattern: %w", err)
+		}
+		filters = append(filters, f)
+		logger.Info("Tag pattern filter enabled", "pattern", opts.TagPattern)
+	}
+
+	if opts.LatestPerGroup != "" {
+		// Tags outside every group are never deletion candidates
+		f, err := filter.NewRegexFilter(opts.LatestPerGroup, false)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keep-latest-per-group pattern: %w", err)
+		}
+		filters = append(filters, f)
+	}
+
+	if opts.ExcludePattern != "" {
+		f, err := filter.NewRegexFilter(opts.ExcludePattern, true)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern: %w", err)
+		}
+		filters = append(filters, f)
+		logger.Info("Exclude pattern filter enabled", "pattern", opts.ExcludePattern)
+	}
+
+	if opts.HasArch != "" {
+		f, err := filter.NewPlatformFilter(opts.HasArch, false)
+		if err != nil {
+			return nil, fmt.Errorf("invalid has-arch platform: %w", err)
+		}
+		filters = append(filters, f)
+		logger.Info("Platform filter enabled", "has_arch", opts.HasArch)
+	}
+
+	if opts.LacksArch != "" {
+		f, err := filter.NewPlatformFilter(opts.LacksArch, true)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lacks-arch platform: %w", err)
+		}
+		filters = append(filters, f)
+		logger.Info("Platform filter enabled", "lacks_arch", opts.LacksArch)
+	}
+
+	if opts.Status != filter.StatusAll {
+		f, err := filter.NewStatusFilter(opts.Status)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+		logger.Info("Tag status filter enabled", "status", opts.Status)
+	}
+
+	if opts.VersionRange != "" {
+		// Reuse the semver sorter's prefix stripping so prefixed tags parse correctly
+		versioner, err := newVersioner(opts)
+		if err != nil {
+			return nil, err
+		}
+		f, err := filter.NewSemverRangeFilter(opts.VersionRange, versioner.Version, opts.RangeInvalid)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version range: %w", err)
+		}
+		filters = append(filters, f)
+		logger.Info("Version range filter enabled", "range", opts.VersionRange, "include_invalid", opts.RangeInvalid)
+	}
+
+	if opts.TagLengthMin > 0 || opts.TagLengthMax > 0 || opts.TagCharset != "" {
+		f, err := filter.NewShapeFilter(opts.TagLengthMin, opts.TagLengthMax, opts.TagCharset)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+		logger.Info("Tag shape filter enabled", "min_length", opts.TagLengthMin, "max_length", opts.TagLengthMax, "charset", opts.TagCharset)
+	}
+
+	if opts.MinAge > 0 {
+		filters = append(filters, filter.NewMinAgeFilter(opts.MinAge, opts.AgeField))
+		logger.Info("Minimum age filter enabled", "min_age", opts.MinAge, "age_field", opts.AgeField)
+	}
+
+	if opts.LabelSelector != "" {
+		f, err := filter.NewLabelFilter(opts.LabelSelector)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+		logger.Info("Label filter enabled (one registry lookup per image)", "selector", opts.LabelSelector)
+	}
+
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	return filter.NewCompositeFilter(filters...), nil
+}
+
+// normalizer creates the tag name view transform (nil if not configured)
+func normalizer(opts Options) (sortpkg.NameTransform, error) {
+	if opts.TagNormalize == "" {
+		return nil, nil
+	}
+	t, err := sortpkg.NewRegexReplace(opts.TagNormalize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag-normalize rule: %w", err)
+	}
+	return t, nil
+}
+
+// newVersioner creates a semver sorter honoring normalization and prefix stripping
+func newVersioner(opts Options) (*sortpkg.SemverSorter, error) {
+	normalize, err := normalizer(opts)
+	if err != nil {
+		return nil, err
+	}
+	s, err := sortpkg.NewSemverSorter(opts.StripPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid strip-prefix pattern: %w", err)
+	}
+	return s.WithNormalize(normalize), nil
+}
+
+// newNumericSorter creates a numeric-dotted sorter honoring normalization and
+// prefix stripping
+func newNumericSorter(opts Options) (*sortpkg.NumericSorter, error) {
+	normalize, err := normalizer(opts)
+	if err != nil {
+		return nil, err
+	}
+	s, err := sortpkg.NewNumericSorter(opts.StripPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid strip-prefix pattern: %w", err)
+	}
+	return s.WithNormalize(normalize), nil
+}
+
+// buildSorter creates the tag sorter from options
+func buildSorter(opts Options) (sortpkg.TagSorter, error) {
+	logger := opts.Logger
+
+	switch opts.SortMethod {
+	case SortLexicographical:
+		normalize, err := normalizer(opts)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Using lexicographical sorting")
+		return sortpkg.NewLexicographicalSorter().WithNormalize(normalize), nil
+	case SortSemver:
+		s, err := newVersioner(opts)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Using semver sorting")
+		if opts.StripPrefix != "" {
+			logger.Info("Strip prefix enabled", "pattern", opts.StripPrefix)
+		}
+		if opts.TagNormalize != "" {
+			logger.Info("Tag normalization enabled", "rule", opts.TagNormalize)
+		}
+		if opts.SemverTiebreak != "" {
+			s.WithTiebreak(sortpkg.Tiebreak(opts.SemverTiebreak), opts.AgeField)
+		}
+		if opts.PrereleaseFirst {
+			s.WithPrereleaseFirst()
+			logger.Info("Ordering prereleases ahead of their release")
+		}
+		switch opts.FallbackSort {
+		case SortDate:
+			s.WithFallback(sortpkg.NewDateSorter(opts.AgeField))
+			logger.Info("Sorting non-semver tags by date", "age_field", opts.AgeField)
+		case SortNumeric:
+			n, err := newNumericSorter(opts)
+			if err != nil {
+				return nil, err
+			}
+			s.WithFallback(n)
+			logger.Info("Sorting non-semver tags as numeric-dotted versions")
+		}
+		return s, nil
+	case SortNumeric:
+		s, err := newNumericSorter(opts)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Using numeric-dotted sorting")
+		if opts.StripPrefix != "" {
+			logger.Info("Strip prefix enabled", "pattern", opts.StripPrefix)
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("invalid sort method: %s (must be 'lexicographical', 'semver' or 'numeric-dotted')", opts.SortMethod)
+	}
+}
+
+// location loads the --timezone location (nil for the default rolling UTC cutoff)
+func location(name string) (*time.Location, error) {
+	if name == "" {
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone: %w", err)
+	}
+	return loc, nil
+}
+
+// buildPolicy creates the retention policy from options
+// The sorted parameter should contain the filtered tags in sort order,
+// inUse the deployed tags (nil if no in-use source is configured) and pinned
+// the digest-pinned protection (nil if not configured)
+func buildPolicy(opts Options, sorted []api.Tag, inUse []string, pinned policy.RetentionPolicy) (policy.RetentionPolicy, error) {
+	logger := opts.Logger
+	var policies []policy.RetentionPolicy
+	var keeps []policy.RetentionPolicy // kept whatever the policy mode
+
+	loc, err := location(opts.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	if loc != nil && opts.KeepDays > 0 {
+		logger.Info("Counting days from local midnight", "timezone", loc, "cutoff", policy.Cutoff(opts.KeepDays, loc))
+	}
+
+	// Tags matched by a rule are left out of the other retention policies
+	all := sorted
+	var rules []policy.Rule
+	if len(opts.Rules) > 0 {
+		rules, sorted, err = buildRules(opts, all, loc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Plain count and days together are a single hybrid policy, so both
+	// rules share one cutoff and one sorted view of the tags
+	hybrid := opts.KeepDays > 0 && opts.KeepCount > 0 && opts.GroupBy == "" && opts.CountUnit == CountUnitTags &&
+		opts.PolicyMode == PolicyModeOR
+
+	if hybrid {
+		policies = append(policies, policy.NewHybridRetentionPolicy(opts.KeepCount, opts.KeepDays, opts.AgeField, loc, sorted))
+		logger.Info("Hybrid retention policy enabled (keep last N tags plus tags newer than X days)",
+			"count", opts.KeepCount, "days", opts.KeepDays, "age_field", opts.AgeField)
+	} else if opts.KeepDays > 0 {
+		policies = append(policies, policy.NewDaysRetentionPolicy(opts.KeepDays, opts.AgeField).WithTimezone(loc))
+		logger.Info("Days retention policy enabled", "days", opts.KeepDays, "age_field", opts.AgeField)
+	}
+
+	if opts.PolicyMode == PolicyModeSizeFloor {
+		// Use sorted tags: the newest tags fill the floor and then the budget
+		policies = append(policies, policy.NewSizeFloorCountPolicy(opts.KeepCount, opts.KeepSize, sorted))
+		logger.Info("Size-floor retention policy enabled (keep last N tags, then older tags within the size budget)",
+			"count", opts.KeepCount, "size_budget", opts.KeepSize)
+	} else if opts.KeepCount > 0 && opts.GroupBy != "" {
+		// Use sorted tags for count policy, bucketed by group key
+		normalize, err := normalizer(opts)
+		if err != nil {
+			return nil, err
+		}
+		p, err := policy.NewGroupedCountPolicy(opts.KeepCount, opts.GroupBy, normalize, sorted)
+		if err != nil {
+			return nil, fmt.Errorf("invalid group-by pattern: %w", err)
+		}
+		policies = append(policies, p)
+		logger.Info("Grouped count retention policy enabled", "count", opts.KeepCount, "group_by", opts.GroupBy)
+	} else if opts.KeepCount > 0 && opts.CountUnit == CountUnitManifests {
+		// Use sorted tags for count policy, counting each manifest once
+		policies = append(policies, policy.NewManifestCountPolicy(opts.KeepCount, manifestKey, sorted))
+		logger.Info("Manifest count retention policy enabled", "count", opts.KeepCount)
+	} else if opts.KeepCount > 0 && !hybrid {
+		// Use sorted tags for count policy
+		policies = append(policies, policy.NewCountRetentionPolicy(opts.KeepCount, sorted))
+		logger.Info("Count retention policy enabled", "count", opts.KeepCount)
+	}
+
+	if opts.MaxSize > 0 {
+		// Use sorted tags: the newest tags fill the budget
+		policies = append(policies, policy.NewSizeRetentionPolicy(opts.MaxSize, sorted))
+		logger.Info("Size retention policy enabled (keep the newest tags within the size budget)", "max_size", opts.MaxSize)
+	}
+
+	if opts.LatestPerGroup != "" {
+		// Use sorted tags: the first tag seen in each group is its newest
+		p, err := policy.NewGroupedCountPolicy(1, opts.LatestPerGroup, nil, sorted)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keep-latest-per-group pattern: %w", err)
+		}
+		policies = append(policies, p)
+		logger.Info("Latest-per-group retention policy enabled", "pattern", opts.LatestPerGroup)
+	}
+
+	if opts.KeepPulledWithin > 0 {
+		p := policy.NewLastPulledRetentionPolicy(opts.KeepPulledWithin, opts.KeepNeverPulled).WithTimezone(loc)
+		if opts.PolicyMode == PolicyModeAND {
+			// A recent pull protects a tag; ANDed in, it would delete pulled tags the others keep
+			keeps = append(keeps, p)
+			logger.Warn("--keep-pulled-within keeps recently pulled tags on its own in AND policy mode")
+		} else {
+			policies = append(policies, p)
+		}
+		logger.Info("Last-pulled retention policy enabled", "days", opts.KeepPulledWithin, "keep_never_pulled", opts.KeepNeverPulled)
+	}
+
+	if opts.DeletePattern != "" {
+		p, err := policy.NewDeletePatternPolicy(opts.DeletePattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid delete pattern: %w", err)
+		}
+		policies = append(policies, p)
+		logger.Info("Delete pattern policy enabled (matching tags are deleted)", "pattern", opts.DeletePattern)
+	}
+
+	if opts.KeepPerMajor > 0 || opts.KeepPerMinor > 0 {
+		// Use sorted tags: the semver sorter puts each series' newest tags first
+		versioner, err := newVersioner(opts)
+		if err != nil {
+			return nil, err
+		}
+		if opts.KeepPerMajor > 0 {
+			policies = append(policies, policy.NewSemverSeriesPolicy(opts.KeepPerMajor, policy.SeriesMajor, versioner.Version, sorted))
+			logger.Info("Per-major retention policy enabled", "count", opts.KeepPerMajor)
+		}
+		if opts.KeepPerMinor > 0 {
+			policies = append(policies, policy.NewSemverSeriesPolicy(opts.KeepPerMinor, policy.SeriesMinor, versioner.Version, sorted))
+			logger.Info("Per-minor retention policy enabled", "count", opts.KeepPerMinor)
+		}
+	}
+
+	if opts.KeepHighest {
+		// Reuse the semver sorter's normalization and prefix stripping
+		versioner, err := newVersioner(opts)
+		if err != nil {
+			return nil, err
+		}
+		keeps = append(keeps, policy.NewHighestSemverPolicy(versioner.Version, all))
+		logger.Info("Highest semver policy enabled (current release always kept)")
+	}
+
+	if opts.KeepPrereleases {
+		// Reuse the semver sorter's prefix stripping so prefixed tags parse correctly
+		versioner, err := newVersioner(opts)
+		if err != nil {
+			return nil, err
+		}
+		keeps = append(keeps, policy.NewPrereleasePolicy(versioner.Version).KeepPrereleases())
+		logger.Info("Prerelease exclusion enabled (prereleases always kept)")
+	}
+
+	if opts.KeepPattern != "" {
+		p, err := policy.NewPatternKeepPolicy(opts.KeepPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keep pattern: %w", err)
+		}
+		keeps = append(keeps, p)
+		logger.Info("Pattern keep policy enabled", "pattern", opts.KeepPattern)
+	}
+
+	if opts.PolicyMode == PolicyModeAND && len(policies) > 1 {
+		// Use AND mode for the retention rules: keep only if ALL of them say to keep
+		policies = []policy.RetentionPolicy{policy.NewCompositePolicy(policy.PolicyModeAND, policies...)}
+		logger.Info("Using AND policy mode (keep only if ALL retention policies match)")
+	}
+	if len(rules) > 0 {
+		// The first matching rule decides; the policies above decide the other tags
+		var fallback policy.RetentionPolicy
+		switch len(policies) {
+		case 0:
+		case 1:
+			fallback = policies[0]
+		default:
+			fallback = policy.NewCompositePolicy(policy.PolicyModeOR, policies...)
+		}
+		policies = []policy.RetentionPolicy{policy.NewRulesPolicy(rules, fallback)}
+	}
+	// Keep rules keep tags on their own in every mode
+	policies = append(policies, keeps...)
+
+	var retentionPolicy policy.RetentionPolicy
+	if len(policies) == 1 {
+		retentionPolicy = policies[0]
+	} else {
+		// Use OR mode: keep if ANY policy says to keep
+		retentionPolicy = policy.NewCompositePolicy(policy.PolicyModeOR, policies...)
+		if opts.PolicyMode != PolicyModeAND {
+			logger.Info("Using OR policy mode (keep if ANY policy matches)")
+		}
+	}
+
+	if opts.PrunePrereleases {
+		// Reuse the semver sorter's prefix stripping so prefixed tags parse correctly
+		versioner, err := newVersioner(opts)
+		if err != nil {
+			return nil, err
+		}
+		// AND mode: prereleases are deleted regardless of the retention policy
+		retentionPolicy = policy.NewCompositePolicy(policy.PolicyModeAND,
+			retentionPolicy, policy.NewPrereleasePolicy(versioner.Version))
+		logger.Info("Prerelease pruning enabled (stable tags only)")
+	}
+
+	if opts.PruneReleased > 0 {
+		versioner, err := newVersioner(opts)
+		if err != nil {
+			return nil, err
+		}
+		// AND mode: prereleases of old enough releases are deleted regardless of the retention policy
+		retentionPolicy = policy.NewCompositePolicy(policy.PolicyModeAND, retentionPolicy,
+			policy.NewReleasedPrereleasePolicy(opts.PruneReleased, opts.AgeField, versioner.Version, all).WithTimezone(loc))
+		logger.Info("Released prerelease pruning enabled", "days", opts.PruneReleased, "age_field", opts.AgeField)
+	}
+
+	protection, err := buildProtection(opts, inUse, pinned)
+	if err != nil {
+		return nil, err
+	}
+	if protection != nil {
+		// OR mode last: protected tags are kept whatever the other policies say
+		retentionPolicy = policy.NewCompositePolicy(policy.PolicyModeOR, retentionPolicy, protection)
+	}
+
+	return retentionPolicy, nil
+}
+
+// buildProtection combines the in-use, digest-pinned and --protect
+// protections (nil if none is configured)
+func buildProtection(opts Options, inUse []string, pinned policy.RetentionPolicy) (policy.RetentionPolicy, error) {
+	logger := opts.Logger
+	var policies []policy.RetentionPolicy
+
+	if inUse != nil {
+		policies = append(policies, policy.NewInUsePolicy(inUse))
+		logger.Info("In-use protection enabled", "tags", len(inUse))
+	}
+
+	if pinned != nil {
+		policies = append(policies, pinned)
+		logger.Info("Digest-pinned protection enabled", "source", opts.PinnedDigests)
+	}
+
+	if len(opts.Protect) > 0 {
+		p, err := policy.NewProtectPolicy(opts.Protect)
+		if err != nil {
+			return nil, fmt.Errorf("invalid protect entry: %w", err)
+		}
+		policies = append(policies, p)
+		logger.Info("Protected tags", "entries", opts.Protect)
+	}
+
+	switch len(policies) {
+	case 0:
+		return nil, nil
+	case 1:
+		return policies[0], nil
+	}
+	return policy.NewCompositePolicy(policy.PolicyModeOR, policies...), nil
+}
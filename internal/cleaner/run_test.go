@@ -0,0 +1,296 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+	"github.com/ataraskov/docker-hub-cleaner/internal/filter"
+	"github.com/ataraskov/docker-hub-cleaner/internal/policy"
+)
+
+// roundTripFunc answers HTTP requests without a server
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestConnectForbiddenMetadata(t *testing.T) {
+	forbidden := api.NewClient(api.WithHTTPClient(&http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusForbidden,
+				Body:       io.NopCloser(strings.NewReader("forbidden")),
+				Request:    req,
+			}, nil
+		}),
+	}))
+
+	for _, tt := range []struct {
+		name         string
+		showRepoInfo bool
+		wantErr      bool
+	}{
+		{"pre-flight fails", false, true},
+		{"header only", true, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{
+				Client:       forbidden,
+				Repository:   "org/app",
+				ShowRepoInfo: tt.showRepoInfo,
+				Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+			client, info, err := connect(context.Background(), opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("connect() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (client == nil || info != nil) {
+				t.Errorf("connect() = %v, %v, want the client and no metadata", client, info)
+			}
+		})
+	}
+}
+
+// agedTags returns tags updated the given number of days ago, in that order
+func agedTags(days map[string]int, order ...string) []api.Tag {
+	now := time.Now()
+	tags := make([]api.Tag, len(order))
+	for i, name := range order {
+		tags[i] = api.Tag{Name: name, LastUpdated: now.Add(-time.Duration(days[name])*24*time.Hour - time.Hour)}
+	}
+	return tags
+}
+
+// kept returns the names of the tags the policy keeps
+func kept(p policy.RetentionPolicy, tags []api.Tag) []string {
+	var out []string
+	for _, tag := range tags {
+		if p.ShouldKeep(tag) {
+			out = append(out, tag.Name)
+		}
+	}
+	return out
+}
+
+func TestBuildPolicyAndMode(t *testing.T) {
+	days := map[string]int{"a": 0, "b": 1, "c": 2, "d": 3, "e": 40}
+	order := []string{"a", "b", "c", "d", "e"}
+
+	for _, tt := range []struct {
+		name   string
+		mode   string
+		pulled int
+		want   []string
+	}{
+		// OR: the hybrid policy keeps the 3 newest plus anything newer than 2 days
+		{"or", PolicyModeOR, 0, []string{"a", "b", "c"}},
+		// AND: no hybrid; kept only if among the 3 newest and newer than 2 days
+		{"and", PolicyModeAND, 0, []string{"a", "b"}},
+		// AND: a recent pull keeps a tag on its own instead of narrowing the AND
+		{"and with last-pulled", PolicyModeAND, 7, []string{"a", "b", "e"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := agedTags(days, order...)
+			tags[4].TagLastPulled = time.Now().Add(-24 * time.Hour)
+
+			opts := Options{
+				KeepDays:         2,
+				KeepCount:        3,
+				KeepPulledWithin: tt.pulled,
+				PolicyMode:       tt.mode,
+				Logger:           slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+			opts.setDefaults()
+			p, err := buildPolicy(opts, tags, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := kept(p, tags); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("kept = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeepLatestPerGroupLeavesOtherTags(t *testing.T) {
+	order := []string{
+		"feature-login-12", "feature-search-7", "feature-login-11", "main", "feature-cart-3",
+		"release-1.0", "feature-search-6", "feature-cart-2", "feature-login-10", "latest",
+	}
+	days := make(map[string]int, len(order))
+	for i, name := range order {
+		days[name] = i
+	}
+	tags := agedTags(days, order...)
+
+	opts := Options{
+		LatestPerGroup: `^feature-([a-z]+)-[0-9]+$`,
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	opts.setDefaults()
+	f, err := buildFilter(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	candidates := filter.FilterTags(tags, f)
+	p, err := buildPolicy(opts, candidates, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var deleted []string
+	for _, tag := range candidates {
+		if !p.ShouldKeep(tag) {
+			deleted = append(deleted, tag.Name)
+		}
+	}
+	// Only older tags of each family are deleted; main, release-1.0 and
+	// latest are not candidates at all
+	want := []string{"feature-login-11", "feature-search-6", "feature-cart-2", "feature-login-10"}
+	if !reflect.DeepEqual(deleted, want) {
+		t.Errorf("deleted = %v, want %v", deleted, want)
+	}
+}
+
+func TestBuildPolicyKeepHighestOldest(t *testing.T) {
+	// The current release is the oldest tag; newer tags are backports,
+	// a prerelease of the next version and a branch tag
+	days := map[string]int{"3.0.0-rc.1": 0, "main": 1, "v1.9.1": 2, "1.8.5": 90, "v2.0.0": 400}
+	order := []string{"3.0.0-rc.1", "main", "v1.9.1", "1.8.5", "v2.0.0"}
+
+	for _, mode := range []string{PolicyModeOR, PolicyModeAND} {
+		t.Run(mode, func(t *testing.T) {
+			tags := agedTags(days, order...)
+			opts := Options{
+				KeepDays:    30,
+				KeepCount:   3,
+				PolicyMode:  mode,
+				KeepHighest: true,
+				Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+			opts.setDefaults()
+			p, err := buildPolicy(opts, tags, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"3.0.0-rc.1", "main", "v1.9.1", "v2.0.0"}
+			if got := kept(p, tags); !reflect.DeepEqual(got, want) {
+				t.Errorf("kept = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestBuildPolicyKeepPrereleases(t *testing.T) {
+	days := map[string]int{"1.1.0": 0, "develop-1.1.0-rc.1": 10, "1.0.0": 20, "1.0.0-beta": 30, "main": 40}
+	order := []string{"1.1.0", "develop-1.1.0-rc.1", "1.0.0", "1.0.0-beta", "main"}
+
+	for _, mode := range []string{PolicyModeOR, PolicyModeAND} {
+		t.Run(mode, func(t *testing.T) {
+			tags := agedTags(days, order...)
+			opts := Options{
+				KeepDays:        5,
+				KeepCount:       1,
+				PolicyMode:      mode,
+				KeepPrereleases: true,
+				StripPrefix:     "develop-",
+				Logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+			opts.setDefaults()
+			p, err := buildPolicy(opts, tags, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"1.1.0", "develop-1.1.0-rc.1", "1.0.0-beta"}
+			if got := kept(p, tags); !reflect.DeepEqual(got, want) {
+				t.Errorf("kept = %v, want %v", got, want)
+			}
+		})
+	}
+
+	opts := Options{Token: "t", Repository: "org/app", KeepDays: 5, KeepPrereleases: true, PrunePrereleases: true}
+	opts.setDefaults()
+	if err := opts.validate(); err == nil || !strings.Contains(err.Error(), "--exclude-semver-prerelease") {
+		t.Errorf("validate() = %v, want --exclude-semver-prerelease rejected with --prune-prereleases", err)
+	}
+}
+
+// repoRegistry records whether the repository was deleted
+type repoRegistry struct {
+	api.Registry
+
+	tags    []api.Tag
+	deleted bool
+}
+
+func (r *repoRegistry) ListTags(ctx context.Context, repo string) ([]api.Tag, error) {
+	return r.tags, nil
+}
+
+func (r *repoRegistry) DeleteRepository(ctx context.Context, repo string) error {
+	r.deleted = true
+	return nil
+}
+
+func (r *repoRegistry) MaxUsefulConcurrency() int { return 1 }
+
+func TestDeleteRepositoryConfirm(t *testing.T) {
+	tags := testTags("v1", "v2")
+	all := func(repo string, tags []api.Tag) ([]api.Tag, error) { return tags, nil }
+	some := func(repo string, tags []api.Tag) ([]api.Tag, error) { return tags[:1], nil }
+	refuse := func(repo string, tags []api.Tag) ([]api.Tag, error) { return nil, errors.New("typed nothing") }
+
+	for _, tt := range []struct {
+		name        string
+		confirm     ConfirmFunc
+		dryRun      bool
+		wantDeleted bool
+		wantErr     error
+	}{
+		{"no callback", nil, false, true, nil},
+		{"approved", all, false, true, nil},
+		{"partly approved", some, false, false, ErrNotConfirmed},
+		{"refused", refuse, false, false, ErrNotConfirmed},
+		{"dry run does not ask", refuse, true, false, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen []api.Tag
+			reg := &repoRegistry{tags: tags}
+			opts := Options{
+				Client:     reg,
+				Repository: "org/app",
+				DryRun:     tt.dryRun,
+				Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+			if tt.confirm != nil {
+				opts.Confirm = func(repo string, tags []api.Tag) ([]api.Tag, error) {
+					seen = tags
+					return tt.confirm(repo, tags)
+				}
+			}
+
+			n, err := DeleteRepository(context.Background(), opts)
+			if !errors.Is(err, tt.wantErr) || (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("DeleteRepository() error = %v, want %v", err, tt.wantErr)
+			}
+			if n != len(tags) {
+				t.Errorf("DeleteRepository() = %d tags, want %d", n, len(tags))
+			}
+			if reg.deleted != tt.wantDeleted {
+				t.Errorf("repository deleted = %v, want %v", reg.deleted, tt.wantDeleted)
+			}
+			if tt.confirm != nil && !tt.dryRun && len(seen) != len(tags) {
+				t.Errorf("Confirm saw %d tags, want all %d", len(seen), len(tags))
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package cleaner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/reference"
+)
+
+// ReadTagList reads one entry per line, skipping blank lines and # comments
+func ReadTagList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// TagsFor returns the tag names that apply to repo: entries are tag names,
+// or image references (repo:tag, docker.io/org/app:tag, nginx:tag) that
+// only apply to that repository. A reference without a tag, such as
+// app@sha256:..., names no tag and is an error, as is one that cannot be parsed.
+func TagsFor(refs []string, repo string) ([]string, error) {
+	tags := []string{}
+	for _, entry := range refs {
+		if !strings.ContainsAny(entry, ":/@") {
+			tags = append(tags, entry)
+			continue
+		}
+		ref, err := reference.Parse(entry)
+		if err != nil {
+			return nil, err
+		}
+		if ref.Tag == "" {
+			return nil, fmt.Errorf("image reference %q names no tag (list digests with --keep-digest-pinned-source)", entry)
+		}
+		if reference.SameRepository(ref.Name, repo) {
+			tags = append(tags, ref.Tag)
+		}
+	}
+	return tags, nil
+}
+
+// DigestsFor returns the digests that apply to repo: entries are digests
+// (sha256:...), or image references with a digest (repo@sha256:...,
+// docker.io/org/app:1.2@sha256:..., nginx@sha256:...) that only apply to
+// that repository. An entry that cannot be parsed or names no digest is an error.
+func DigestsFor(refs []string, repo string) ([]string, error) {
+	digests := []string{}
+	for _, entry := range refs {
+		if reference.IsDigest(entry) {
+			digests = append(digests, entry)
+			continue
+		}
+		ref, err := reference.Parse(entry)
+		if err != nil {
+			return nil, err
+		}
+		if ref.Digest == "" {
+			return nil, fmt.Errorf("image reference %q names no digest", entry)
+		}
+		if reference.SameRepository(ref.Name, repo) {
+			digests = append(digests, ref.Digest)
+		}
+	}
+	return digests, nil
+}
+
+// RemainingTags returns the names of fetched tags that were not deleted
+// (or would not be, in dry-run), sorted by name
+func (r *CleanResult) RemainingTags() []string {
+	deleted := make(map[string]bool, len(r.DeletedTags))
+	for _, name := range r.DeletedTags {
+		deleted[name] = true
+	}
+
+	var names []string
+	for _, tag := range r.Fetched {
+		if !deleted[tag.Name] {
+			names = append(names, tag.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// KeepDiff lists where the remaining tags differ from an expected list
+type KeepDiff struct {
+	UnexpectedDeletions []string // expected to remain but deleted (or missing)
+	UnexpectedSurvivals []string // remaining but not expected
+}
+
+// Empty reports whether the remaining tags match the expected list
+func (d KeepDiff) Empty() bool {
+	return len(d.UnexpectedDeletions) == 0 && len(d.UnexpectedSurvivals) == 0
+}
+
+// DiffRemaining compares the tags remaining after a run with expected
+func DiffRemaining(expected []string, result *CleanResult) KeepDiff {
+	want := make(map[string]bool, len(expected))
+	for _, name := range expected {
+		want[name] = true
+	}
+
+	var diff KeepDiff
+	have := make(map[string]bool)
+	for _, name := range result.RemainingTags() {
+		have[name] = true
+		if !want[name] {
+			diff.UnexpectedSurvivals = append(diff.UnexpectedSurvivals, name)
+		}
+	}
+	for name := range want {
+		if !have[name] {
+			diff.UnexpectedDeletions = append(diff.UnexpectedDeletions, name)
+		}
+	}
+	sort.Strings(diff.UnexpectedDeletions)
+	return diff
+}
@@ -0,0 +1,74 @@
+package cleaner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTagsFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		repo    string
+		refs    []string
+		want    []string
+		wantErr bool
+	}{
+		{"bare tag applies everywhere", "org/app", []string{"1.2"}, []string{"1.2"}, false},
+		{"repo:tag", "org/app", []string{"org/app:1.2", "org/other:1.3"}, []string{"1.2"}, false},
+		{"docker.io host", "org/app", []string{"docker.io/org/app:1.2"}, []string{"1.2"}, false},
+		{"index.docker.io host", "org/app", []string{"index.docker.io/org/app:1.2"}, []string{"1.2"}, false},
+		{"library image", "library/nginx", []string{"nginx:1.2", "docker.io/library/nginx:1.3"}, []string{"1.2", "1.3"}, false},
+		{"library image elsewhere", "org/nginx", []string{"nginx:1.2"}, []string{}, false},
+		{"tag and digest", "org/app", []string{"org/app:1.2@sha256:abababababababababababababababababababababababababababababababab"}, []string{"1.2"}, false},
+		{"other registry", "org/app", []string{"ghcr.io/org/app:1.2"}, []string{"1.2"}, false},
+		{"registry with port", "team/app", []string{"localhost:5000/team/app:1.2"}, []string{"1.2"}, false},
+		{"digest only", "org/app", []string{"app@sha256:abababababababababababababababababababababababababababababababab"}, nil, true},
+		{"bad tag", "org/app", []string{"org/app:"}, nil, true},
+		{"bad name", "org/app", []string{"Org/App:1.2"}, nil, true},
+		{"bad digest", "org/app", []string{"org/app:1.2@latest"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TagsFor(tt.refs, tt.repo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TagsFor() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TagsFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDigestsFor(t *testing.T) {
+	const d1 = "sha256:abababababababababababababababababababababababababababababababab"
+	const d2 = "sha256:cdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcd"
+	tests := []struct {
+		name    string
+		repo    string
+		refs    []string
+		want    []string
+		wantErr bool
+	}{
+		{"bare digest applies everywhere", "org/app", []string{d1}, []string{d1}, false},
+		{"repo@digest", "org/app", []string{"org/app@" + d1, "org/other@" + d2}, []string{d1}, false},
+		{"docker.io host", "org/app", []string{"docker.io/org/app@" + d1}, []string{d1}, false},
+		{"library image", "library/nginx", []string{"nginx@" + d1}, []string{d1}, false},
+		{"tag and digest", "org/app", []string{"org/app:1.2@" + d2}, []string{d2}, false},
+		{"no digest", "org/app", []string{"org/app:1.2"}, nil, true},
+		{"bad digest", "org/app", []string{"org/app@sha256:xyz"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DigestsFor(tt.refs, tt.repo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DigestsFor() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DigestsFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// MinAgeFilter matches tags at least a given age old, so younger tags are
+// never deletion candidates (and are not counted by retention policies)
+type MinAgeFilter struct {
+	min   time.Duration
+	field api.AgeField
+	now   func() time.Time
+}
+
+// NewMinAgeFilter creates a new minimum age filter
+// The field selects which tag timestamp determines the age
+func NewMinAgeFilter(min time.Duration, field api.AgeField) *MinAgeFilter {
+	return &MinAgeFilter{
+		min:   min,
+		field: field,
+		now:   time.Now,
+	}
+}
+
+// Matches returns true as the age cannot be determined from the name alone
+func (f *MinAgeFilter) Matches(tag string) bool {
+	return true
+}
+
+// MatchesTag returns true if the tag is at least the minimum age
+func (f *MinAgeFilter) MatchesTag(tag api.Tag) bool {
+	return !tag.Time(f.field).After(f.now().Add(-f.min))
+}
+
+// ParseAge parses an age such as "7d", "2w" or any time.ParseDuration
+// value ("36h"); a day is 24 hours
+func ParseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil || v < 0 {
+				return 0, fmt.Errorf("invalid age %q (e.g., 7d, 2w or 36h)", s)
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q (e.g., 7d, 2w or 36h)", s)
+	}
+	return d, nil
+}
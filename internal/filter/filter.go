@@ -12,6 +12,21 @@ type TagFilter interface {
 	Matches(tag string) bool
 }
 
+// TagAwareFilter is implemented by filters that need the full tag
+// (e.g., its images) rather than only the tag name
+type TagAwareFilter interface {
+	TagFilter
+	MatchesTag(tag api.Tag) bool
+}
+
+// matchTag applies a filter to a tag, using MatchesTag when available
+func matchTag(filter TagFilter, tag api.Tag) bool {
+	if f, ok := filter.(TagAwareFilter); ok {
+		return f.MatchesTag(tag)
+	}
+	return filter.Matches(tag.Name)
+}
+
 // RegexFilter filters tags based on a regex pattern
 type RegexFilter struct {
 	pattern *regexp.Regexp
@@ -62,6 +77,63 @@ func (f *CompositeFilter) Matches(tag string) bool {
 	return true
 }
 
+// MatchesTag returns true if all filters match the full tag (AND logic)
+func (f *CompositeFilter) MatchesTag(tag api.Tag) bool {
+	for _, filter := range f.filters {
+		if !matchTag(filter, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// NotFilter negates another filter
+type NotFilter struct {
+	filter TagFilter
+}
+
+// NewNotFilter creates a filter matching tags the given filter rejects
+func NewNotFilter(filter TagFilter) *NotFilter {
+	return &NotFilter{
+		filter: filter,
+	}
+}
+
+// Matches returns true if the wrapped filter does not match
+// A filter that needs the full tag cannot be decided from the name alone,
+// so its negation matches every name and MatchesTag decides
+func (f *NotFilter) Matches(tag string) bool {
+	if needsTag(f.filter) {
+		return true
+	}
+	return !f.filter.Matches(tag)
+}
+
+// MatchesTag returns true if the wrapped filter does not match the full tag
+func (f *NotFilter) MatchesTag(tag api.Tag) bool {
+	return !matchTag(f.filter, tag)
+}
+
+// needsTag reports whether a filter, or any filter it combines, can only be
+// decided from the full tag (its name-only Matches always returns true)
+func needsTag(filter TagFilter) bool {
+	switch f := filter.(type) {
+	case *NotFilter:
+		return needsTag(f.filter)
+	case *CompositeFilter:
+		for _, member := range f.filters {
+			if needsTag(member) {
+				return true
+			}
+		}
+		return false
+	case TagAwareFilter:
+		return true
+	default:
+		return false
+	}
+}
+
 // FilterTags filters tags based on the provided filter
 func FilterTags(tags []api.Tag, filter TagFilter) []api.Tag {
 	if filter == nil {
@@ -70,7 +142,7 @@ func FilterTags(tags []api.Tag, filter TagFilter) []api.Tag {
 
 	var filtered []api.Tag
 	for _, tag := range tags {
-		if filter.Matches(tag.Name) {
+		if matchTag(filter, tag) {
 			filtered = append(filtered, tag)
 		}
 	}
@@ -0,0 +1,78 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+func TestNotFilterDoubleNegation(t *testing.T) {
+	a, err := NewRegexFilter(`^feature-`, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewRegexFilter(`-rc\d+$`, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filters := map[string]TagFilter{
+		"regex":     a,
+		"composite": NewCompositeFilter(a, b),
+	}
+	names := []string{"feature-x", "feature-x-rc1", "v1.0.0-rc1", "v1.0.0", ""}
+
+	for label, f := range filters {
+		not := NewNotFilter(f)
+		notNot := NewNotFilter(not)
+		for _, name := range names {
+			if got, want := notNot.Matches(name), f.Matches(name); got != want {
+				t.Errorf("%s: Not(Not(f)).Matches(%q) = %v, want %v", label, name, got, want)
+			}
+			if got, want := not.Matches(name), !f.Matches(name); got != want {
+				t.Errorf("%s: Not(f).Matches(%q) = %v, want %v", label, name, got, want)
+			}
+			tag := api.Tag{Name: name}
+			if got, want := notNot.MatchesTag(tag), matchTag(f, tag); got != want {
+				t.Errorf("%s: Not(Not(f)).MatchesTag(%q) = %v, want %v", label, name, got, want)
+			}
+		}
+	}
+}
+
+func TestNotFilterTagAware(t *testing.T) {
+	f, err := NewLabelFilter("env=ephemeral")
+	if err != nil {
+		t.Fatal(err)
+	}
+	not := NewNotFilter(f)
+
+	// The name alone cannot decide a label filter, so every name passes the prefilter
+	if !not.Matches("v1.0.0") {
+		t.Error("Not(label).Matches() = false, want true")
+	}
+
+	feature, err := NewRegexFilter(`^feature-`, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !NewNotFilter(NewCompositeFilter(feature, f)).Matches("feature-x") {
+		t.Error("Not(regex AND label).Matches() = false, want true")
+	}
+
+	ephemeral := api.Tag{Name: "pr-1", Labels: map[string]string{"env": "ephemeral"}}
+	stable := api.Tag{Name: "v1.0.0", Labels: map[string]string{"env": "prod"}}
+	if not.MatchesTag(ephemeral) {
+		t.Error("Not(label).MatchesTag(ephemeral) = true, want false")
+	}
+	if !not.MatchesTag(stable) {
+		t.Error("Not(label).MatchesTag(stable) = false, want true")
+	}
+
+	got := FilterTags([]api.Tag{ephemeral, stable}, not)
+	if len(got) != 1 || got[0].Name != "v1.0.0" {
+		t.Errorf("FilterTags() = %v, want [v1.0.0]", got)
+	}
+	if notNot := NewNotFilter(not); notNot.MatchesTag(stable) || !notNot.MatchesTag(ephemeral) {
+		t.Error("Not(Not(label)) does not match like label")
+	}
+}
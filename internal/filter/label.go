@@ -0,0 +1,60 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// labelRequirement is a single key=value or key-exists predicate
+type labelRequirement struct {
+	key   string
+	value string
+	exact bool // if false, only the key must be present
+}
+
+// LabelFilter filters tags by their image labels
+// Labels must have been fetched into api.Tag.Labels beforehand
+type LabelFilter struct {
+	requirements []labelRequirement
+}
+
+// NewLabelFilter creates a new label filter from a selector such as
+// "env=ephemeral" or "expires". Comma-separated requirements must all match.
+func NewLabelFilter(selector string) (*LabelFilter, error) {
+	f := &LabelFilter{}
+
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		key, value, exact := strings.Cut(part, "=")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("invalid label selector %q (expected key=value or key)", selector)
+		}
+		f.requirements = append(f.requirements, labelRequirement{
+			key:   key,
+			value: strings.TrimSpace(value),
+			exact: exact,
+		})
+	}
+
+	return f, nil
+}
+
+// Matches returns true as labels cannot be determined from the name alone
+func (f *LabelFilter) Matches(tag string) bool {
+	return true
+}
+
+// MatchesTag returns true if the tag's labels satisfy every requirement
+// Tags without fetched labels never match
+func (f *LabelFilter) MatchesTag(tag api.Tag) bool {
+	for _, r := range f.requirements {
+		value, ok := tag.Labels[r.key]
+		if !ok || (r.exact && value != r.value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// PlatformFilter filters tags by the presence of an image for a platform
+type PlatformFilter struct {
+	os     string // empty matches any OS
+	arch   string
+	invert bool // if true, match tags lacking the platform
+}
+
+// NewPlatformFilter creates a new platform filter
+// The platform is given as "os/arch" (e.g., linux/amd64) or just "arch".
+// Tags without any images never have the platform.
+func NewPlatformFilter(platform string, invert bool) (*PlatformFilter, error) {
+	f := &PlatformFilter{invert: invert}
+
+	parts := strings.Split(platform, "/")
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		f.arch = parts[0]
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		f.os, f.arch = parts[0], parts[1]
+	default:
+		return nil, fmt.Errorf("invalid platform %q (expected os/arch or arch)", platform)
+	}
+
+	return f, nil
+}
+
+// Matches returns true as the platform cannot be determined from the name alone
+func (f *PlatformFilter) Matches(tag string) bool {
+	return true
+}
+
+// MatchesTag returns true if the tag has (or, inverted, lacks) the platform
+func (f *PlatformFilter) MatchesTag(tag api.Tag) bool {
+	has := false
+	for _, image := range tag.Images {
+		if image.Architecture == f.arch && (f.os == "" || image.OS == f.os) {
+			has = true
+			break
+		}
+	}
+	if f.invert {
+		return !has
+	}
+	return has
+}
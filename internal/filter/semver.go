@@ -0,0 +1,90 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// comparator is a single version constraint such as ">=1.0.0"
+type comparator struct {
+	op      string
+	version string
+}
+
+// satisfied returns true if v satisfies the comparator
+func (c comparator) satisfied(v string) bool {
+	cmp := semver.Compare(v, c.version)
+	switch c.op {
+	case ">=":
+		return cmp >= 0
+	case ">":
+		return cmp > 0
+	case "<=":
+		return cmp <= 0
+	case "<":
+		return cmp < 0
+	default:
+		return cmp == 0
+	}
+}
+
+// SemverRangeFilter matches tags whose version falls within a range
+// The range is a space-separated list of comparators that must all hold,
+// e.g. ">=1.0.0 <2.0.0". Supported operators: >=, >, <=, <, = (or none).
+type SemverRangeFilter struct {
+	comparators  []comparator
+	version      func(name string) string
+	matchInvalid bool // if true, tags that are not valid semver match
+}
+
+// NewSemverRangeFilter creates a new semver range filter
+// The version function maps a tag name to a "v"-prefixed semver string
+func NewSemverRangeFilter(constraint string, version func(name string) string, matchInvalid bool) (*SemverRangeFilter, error) {
+	f := &SemverRangeFilter{
+		version:      version,
+		matchInvalid: matchInvalid,
+	}
+
+	for _, part := range strings.Fields(constraint) {
+		op := ""
+		for _, candidate := range []string{">=", "<=", ">", "<", "="} {
+			if strings.HasPrefix(part, candidate) {
+				op = candidate
+				break
+			}
+		}
+
+		v := strings.TrimPrefix(part, op)
+		if !strings.HasPrefix(v, "v") {
+			v = "v" + v
+		}
+		if !semver.IsValid(v) {
+			return nil, fmt.Errorf("invalid version %q in range %q", part, constraint)
+		}
+
+		f.comparators = append(f.comparators, comparator{op: op, version: v})
+	}
+
+	if len(f.comparators) == 0 {
+		return nil, fmt.Errorf("empty version range")
+	}
+
+	return f, nil
+}
+
+// Matches returns true if the tag's version satisfies all comparators
+func (f *SemverRangeFilter) Matches(tag string) bool {
+	v := f.version(tag)
+	if !semver.IsValid(v) {
+		return f.matchInvalid
+	}
+
+	for _, c := range f.comparators {
+		if !c.satisfied(v) {
+			return false
+		}
+	}
+	return true
+}
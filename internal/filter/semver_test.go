@@ -0,0 +1,75 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+)
+
+// vVersion maps a tag name to a "v"-prefixed semver string
+func vVersion(name string) string {
+	return "v" + strings.TrimPrefix(name, "v")
+}
+
+func TestSemverRangeFilterBoundaries(t *testing.T) {
+	for _, tt := range []struct {
+		constraint string
+		tag        string
+		want       bool
+	}{
+		{">=1.0.0 <2.0.0", "0.9.9", false},
+		{">=1.0.0 <2.0.0", "1.0.0", true},
+		{">=1.0.0 <2.0.0", "v1.0.0", true},
+		{">=1.0.0 <2.0.0", "1.9.9", true},
+		{">=1.0.0 <2.0.0", "2.0.0", false},
+		// Prereleases sort below their release
+		{">=1.0.0 <2.0.0", "1.0.0-rc.1", false},
+		{">=1.0.0 <2.0.0", "2.0.0-rc.1", true},
+		{">1.0.0", "1.0.0", false},
+		{">1.0.0", "1.0.1", true},
+		{"<=2.0.0", "2.0.0", true},
+		{"<=2.0.0", "2.0.1", false},
+		{"=1.2.3", "1.2.3", true},
+		{"=1.2.3", "1.2.4", false},
+		{"1.2.3", "1.2.3", true},
+		{"v1.2.3", "1.2.3", true},
+		// Build metadata is ignored when comparing
+		{"=1.2.3", "1.2.3+build.5", true},
+		{"<1.2.3", "1.2.3+build.5", false},
+	} {
+		t.Run(tt.constraint+"/"+tt.tag, func(t *testing.T) {
+			f, err := NewSemverRangeFilter(tt.constraint, vVersion, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Matches(tt.tag); got != tt.want {
+				t.Errorf("Matches(%q) = %v, want %v", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSemverRangeFilterInvalidTags(t *testing.T) {
+	for _, matchInvalid := range []bool{false, true} {
+		f, err := NewSemverRangeFilter(">=1.0.0", vVersion, matchInvalid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tag := range []string{"latest", "main", "1.2.3.4", "release-1.2.3"} {
+			if got := f.Matches(tag); got != matchInvalid {
+				t.Errorf("matchInvalid=%v: Matches(%q) = %v, want %v", matchInvalid, tag, got, matchInvalid)
+			}
+		}
+		// Valid versions outside the range never match
+		if f.Matches("0.1.0") {
+			t.Errorf("matchInvalid=%v: Matches(%q) = true, want false", matchInvalid, "0.1.0")
+		}
+	}
+}
+
+func TestNewSemverRangeFilterErrors(t *testing.T) {
+	for _, constraint := range []string{"", "   ", ">=x.y.z", ">=1.0.0 <abc", "~1.2.3"} {
+		if _, err := NewSemverRangeFilter(constraint, vVersion, false); err == nil {
+			t.Errorf("NewSemverRangeFilter(%q) succeeded, want error", constraint)
+		}
+	}
+}
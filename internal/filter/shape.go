@@ -0,0 +1,70 @@
+package filter
+
+import "fmt"
+
+// Tag charsets
+const (
+	CharsetHex    = "hex"
+	CharsetDigits = "digits"
+	CharsetAlnum  = "alnum"
+)
+
+// ShapeFilter filters tags by name length and character set
+type ShapeFilter struct {
+	minLen  int // 0 = no minimum
+	maxLen  int // 0 = no maximum
+	charset func(r rune) bool
+}
+
+// NewShapeFilter creates a new shape filter
+// Lengths are inclusive; 0 disables a bound. An empty charset allows any
+// characters, otherwise every character must belong to it.
+func NewShapeFilter(minLen, maxLen int, charset string) (*ShapeFilter, error) {
+	if minLen < 0 || maxLen < 0 {
+		return nil, fmt.Errorf("tag length bounds must not be negative")
+	}
+	if maxLen > 0 && minLen > maxLen {
+		return nil, fmt.Errorf("minimum tag length %d exceeds maximum %d", minLen, maxLen)
+	}
+
+	f := &ShapeFilter{
+		minLen: minLen,
+		maxLen: maxLen,
+	}
+
+	switch charset {
+	case "":
+	case CharsetHex:
+		f.charset = func(r rune) bool {
+			return (r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+		}
+	case CharsetDigits:
+		f.charset = func(r rune) bool {
+			return r >= '0' && r <= '9'
+		}
+	case CharsetAlnum:
+		f.charset = func(r rune) bool {
+			return (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		}
+	default:
+		return nil, fmt.Errorf("invalid tag charset %q (must be hex, digits or alnum)", charset)
+	}
+
+	return f, nil
+}
+
+// Matches returns true if the tag length is within bounds and all
+// characters belong to the charset
+func (f *ShapeFilter) Matches(tag string) bool {
+	if len(tag) < f.minLen || (f.maxLen > 0 && len(tag) > f.maxLen) {
+		return false
+	}
+	if f.charset != nil {
+		for _, r := range tag {
+			if !f.charset(r) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package filter
+
+import "testing"
+
+func TestShapeFilter(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		min, max int
+		charset  string
+		tag      string
+		want     bool
+	}{
+		// Bounds are inclusive
+		{"below min", 7, 40, CharsetHex, "abc123", false},
+		{"at min", 7, 40, CharsetHex, "abc1234", true},
+		{"at max", 7, 40, CharsetHex, "0123456789abcdef0123456789abcdef01234567", true},
+		{"above max", 7, 40, CharsetHex, "0123456789abcdef0123456789abcdef012345678", false},
+		{"exact length", 8, 8, "", "20240615", true},
+		{"exact length, longer", 8, 8, "", "202406150", false},
+		{"no max", 3, 0, "", "a-very-long-branch-name-with-many-parts", true},
+		{"no min", 0, 3, "", "", true},
+
+		// Mixed character sets
+		{"hex upper and lower", 0, 0, CharsetHex, "DeadBeef01", true},
+		{"hex with g", 0, 0, CharsetHex, "abcdefg", false},
+		{"hex with dash", 0, 0, CharsetHex, "abc-123", false},
+		{"digits", 0, 0, CharsetDigits, "20240615", true},
+		{"digits with dot", 0, 0, CharsetDigits, "2024.06.15", false},
+		{"digits with hex", 0, 0, CharsetDigits, "2024a", false},
+		{"alnum mixed case", 0, 0, CharsetAlnum, "Build42x", true},
+		{"alnum with underscore", 0, 0, CharsetAlnum, "build_42", false},
+		{"alnum with non-ASCII", 0, 0, CharsetAlnum, "café1", false},
+		{"any charset", 0, 0, "", "feature/x_1.2-rc", true},
+
+		// Lengths count bytes, and both checks must pass
+		{"multi-byte length", 0, 5, "", "café1", false},
+		{"length ok, charset not", 7, 7, CharsetHex, "abc-123", false},
+		{"charset ok, length not", 7, 7, CharsetHex, "abc12345", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewShapeFilter(tt.min, tt.max, tt.charset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Matches(tt.tag); got != tt.want {
+				t.Errorf("Matches(%q) = %v, want %v", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewShapeFilterErrors(t *testing.T) {
+	for _, tt := range []struct {
+		min, max int
+		charset  string
+	}{
+		{-1, 0, ""},
+		{0, -1, ""},
+		{10, 9, ""},
+		{0, 0, "base64"},
+		{0, 0, "HEX"},
+	} {
+		if _, err := NewShapeFilter(tt.min, tt.max, tt.charset); err == nil {
+			t.Errorf("NewShapeFilter(%d, %d, %q) succeeded, want error", tt.min, tt.max, tt.charset)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package filter
+
+import (
+	"fmt"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// Tag statuses reported by Docker Hub
+const (
+	StatusActive   = "active"
+	StatusInactive = "inactive"
+	StatusAll      = "all"
+)
+
+// StatusFilter filters tags by their Docker Hub tag_status
+type StatusFilter struct {
+	status string
+}
+
+// NewStatusFilter creates a new status filter
+func NewStatusFilter(status string) (*StatusFilter, error) {
+	switch status {
+	case StatusActive, StatusInactive, StatusAll:
+	default:
+		return nil, fmt.Errorf("invalid tag status %q (must be 'active', 'inactive' or 'all')", status)
+	}
+
+	return &StatusFilter{
+		status: status,
+	}, nil
+}
+
+// Matches returns true as the status cannot be determined from the name alone
+func (f *StatusFilter) Matches(tag string) bool {
+	return true
+}
+
+// MatchesTag returns true if the tag has the configured status
+func (f *StatusFilter) MatchesTag(tag api.Tag) bool {
+	return f.status == StatusAll || tag.TagStatus == f.status
+}
@@ -0,0 +1,52 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// statusPage is a page of the tags endpoint as Docker Hub returns it
+const statusPage = `{"count": 4, "next": null, "results": [
+	{"name": "v3", "tag_status": "active", "last_updated": "2024-05-03T00:00:00Z"},
+	{"name": "v2", "tag_status": "inactive", "last_updated": "2024-05-02T00:00:00Z"},
+	{"name": "v1", "tag_status": "inactive", "last_updated": "2024-05-01T00:00:00Z"},
+	{"name": "old", "last_updated": "2020-01-01T00:00:00Z"}
+]}`
+
+func TestStatusFilter(t *testing.T) {
+	tags, err := api.ReadTags(strings.NewReader(statusPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		status string
+		want   []string
+	}{
+		{StatusActive, []string{"v3"}},
+		{StatusInactive, []string{"v2", "v1"}},
+		// A tag without a status only passes "all"
+		{StatusAll, []string{"v3", "v2", "v1", "old"}},
+	} {
+		t.Run(tt.status, func(t *testing.T) {
+			f, err := NewStatusFilter(tt.status)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, tag := range FilterTags(tags, f) {
+				got = append(got, tag.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewStatusFilter("stale"); err == nil {
+		t.Error("NewStatusFilter(\"stale\") succeeded, want error")
+	}
+}
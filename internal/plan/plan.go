@@ -0,0 +1,147 @@
+package plan
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// Version is the plan file format version
+const Version = 1
+
+// Tag is a tag the plan deletes
+type Tag struct {
+	Name   string `json:"name"`
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+}
+
+// Repository is the planned deletion for one repository
+type Repository struct {
+	Name        string `json:"repository"`
+	Fingerprint string `json:"fingerprint"` // hash of every tag's name and digest when planned
+	TotalTags   int    `json:"total_tags"`
+	Tags        []Tag  `json:"tags"`
+}
+
+// Plan records the tags a run would delete, for review before they are applied
+type Plan struct {
+	Version      int          `json:"version"`
+	Created      time.Time    `json:"created"`
+	Registry     string       `json:"registry"`
+	RegistryURL  string       `json:"registry_url,omitempty"`
+	Repositories []Repository `json:"repositories"`
+}
+
+// New creates an empty plan for a registry
+func New(registry, registryURL string) *Plan {
+	return &Plan{
+		Version:     Version,
+		Created:     time.Now().UTC(),
+		Registry:    registry,
+		RegistryURL: registryURL,
+	}
+}
+
+// Fingerprint hashes the names and digests of a repository's tags, in any
+// order; it changes whenever a tag is added, removed or moved
+func Fingerprint(tags []api.Tag) string {
+	entries := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		entries = append(entries, tag.Name+" "+tag.Digest+"\n")
+	}
+	sort.Strings(entries)
+
+	h := sha256.New()
+	for _, e := range entries {
+		h.Write([]byte(e))
+	}
+	return fmt.Sprintf("sha256:%x", h.Sum(nil))
+}
+
+// NewRepository records the deletion of the named tags out of all the
+// tags fetched from a repository
+func NewRepository(name string, fetched []api.Tag, deleting []string) Repository {
+	byName := make(map[string]api.Tag, len(fetched))
+	for _, tag := range fetched {
+		byName[tag.Name] = tag
+	}
+
+	r := Repository{
+		Name:        name,
+		Fingerprint: Fingerprint(fetched),
+		TotalTags:   len(fetched),
+		Tags:        make([]Tag, 0, len(deleting)),
+	}
+	for _, n := range deleting {
+		tag := byName[n]
+		r.Tags = append(r.Tags, Tag{Name: n, Digest: tag.Digest, Size: tag.FullSize})
+	}
+	return r
+}
+
+// Add records a repository's planned deletion, keeping repositories sorted
+func (p *Plan) Add(r Repository) {
+	p.Repositories = append(p.Repositories, r)
+	sort.Slice(p.Repositories, func(i, j int) bool { return p.Repositories[i].Name < p.Repositories[j].Name })
+}
+
+// Find returns the planned deletion for a repository
+func (p *Plan) Find(repo string) (Repository, bool) {
+	for _, r := range p.Repositories {
+		if r.Name == repo {
+			return r, true
+		}
+	}
+	return Repository{}, false
+}
+
+// Names returns the planned repositories
+func (p *Plan) Names() []string {
+	names := make([]string, 0, len(p.Repositories))
+	for _, r := range p.Repositories {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+// Write stores the plan as indented JSON
+func Write(path string, p *Plan) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal plan: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
+}
+
+// Read loads a plan written by Write
+func Read(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse plan %s: %w", path, err)
+	}
+	if p.Version != Version {
+		return nil, fmt.Errorf("unsupported plan version %d in %s (expected %d)", p.Version, path, Version)
+	}
+	for _, r := range p.Repositories {
+		if r.Name == "" || r.Fingerprint == "" {
+			return nil, errors.New("invalid plan: repository entry without name or fingerprint")
+		}
+	}
+	return &p, nil
+}
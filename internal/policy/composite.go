@@ -58,6 +58,11 @@ func (p *CompositePolicy) ShouldKeep(tag api.Tag) bool {
 	}
 }
 
+// Policies returns the policies combined by this composite
+func (p *CompositePolicy) Policies() []RetentionPolicy {
+	return p.policies
+}
+
 // Name returns the policy name
 func (p *CompositePolicy) Name() string {
 	var names []string
@@ -0,0 +1,37 @@
+package policy
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+func TestCountRetentionKeepsMinOfCountAndFiltered(t *testing.T) {
+	// Tags already filtered and sorted, as the cleaner passes them
+	filtered := []api.Tag{{Name: "v4"}, {Name: "v3"}, {Name: "v2"}, {Name: "v1"}}
+
+	for _, count := range []int{0, 2, 4, 10} {
+		t.Run(fmt.Sprint(count), func(t *testing.T) {
+			p := NewCountRetentionPolicy(count, filtered)
+
+			kept := 0
+			for i, tag := range filtered {
+				keep := p.ShouldKeep(tag)
+				if keep != (i < count) {
+					t.Errorf("ShouldKeep(%s) = %v, want %v", tag.Name, keep, i < count)
+				}
+				if keep {
+					kept++
+				}
+			}
+			if want := min(count, len(filtered)); kept != want {
+				t.Errorf("kept %d tags, want %d", kept, want)
+			}
+			// A tag outside the filtered set never takes a slot
+			if p.ShouldKeep(api.Tag{Name: "excluded"}) {
+				t.Error("ShouldKeep(excluded) = true, want false")
+			}
+		})
+	}
+}
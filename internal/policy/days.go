@@ -6,22 +6,46 @@ import (
 	"github.com/ataraskov/docker-hub-cleaner/internal/api"
 )
 
+// now is the clock used for retention cutoffs
+var now = time.Now
+
+// Cutoff returns the instant X days before now
+// With a nil location the cutoff is rolling (exactly X*24h ago, computed in
+// UTC so DST shifts in the host zone do not move it); otherwise it is the
+// start of the day X days ago in that location
+func Cutoff(days int, loc *time.Location) time.Time {
+	if loc == nil {
+		return now().UTC().AddDate(0, 0, -days)
+	}
+	t := now().In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day()-days, 0, 0, 0, 0, loc)
+}
+
 // DaysRetentionPolicy keeps tags created within X days
 type DaysRetentionPolicy struct {
-	days int
+	days  int
+	field api.AgeField
+	loc   *time.Location // optional: day boundaries in this zone (see Cutoff)
 }
 
 // NewDaysRetentionPolicy creates a new days retention policy
-func NewDaysRetentionPolicy(days int) *DaysRetentionPolicy {
+// The field selects which tag timestamp is compared against the cutoff
+func NewDaysRetentionPolicy(days int, field api.AgeField) *DaysRetentionPolicy {
 	return &DaysRetentionPolicy{
-		days: days,
+		days:  days,
+		field: field,
 	}
 }
 
+// WithTimezone counts days from midnight in loc instead of a rolling cutoff
+func (p *DaysRetentionPolicy) WithTimezone(loc *time.Location) *DaysRetentionPolicy {
+	p.loc = loc
+	return p
+}
+
 // ShouldKeep returns true if the tag was created within the retention period
 func (p *DaysRetentionPolicy) ShouldKeep(tag api.Tag) bool {
-	cutoff := time.Now().AddDate(0, 0, -p.days)
-	return tag.LastUpdated.After(cutoff)
+	return tag.Time(p.field).After(Cutoff(p.days, p.loc))
 }
 
 // Name returns the policy name
@@ -0,0 +1,82 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+func TestCutoffFixedClock(t *testing.T) {
+	defer func(clock func() time.Time) { now = clock }(now)
+
+	tokyo := time.FixedZone("UTC+9", 9*60*60)
+	honolulu := time.FixedZone("UTC-10", -10*60*60)
+
+	for _, tt := range []struct {
+		name string
+		now  time.Time
+		days int
+		loc  *time.Location
+		want time.Time
+	}{
+		{"rolling", time.Date(2024, 3, 10, 1, 30, 0, 0, time.UTC), 1, nil,
+			time.Date(2024, 3, 9, 1, 30, 0, 0, time.UTC)},
+		{"rolling ignores the clock's zone", time.Date(2024, 3, 10, 10, 30, 0, 0, tokyo), 1, nil,
+			time.Date(2024, 3, 9, 1, 30, 0, 0, time.UTC)},
+		{"midnight ahead of UTC", time.Date(2024, 3, 10, 1, 30, 0, 0, time.UTC), 1, tokyo,
+			time.Date(2024, 3, 9, 0, 0, 0, 0, tokyo)},
+		// 20:00 UTC is already the next day in Tokyo and still the same day in Honolulu
+		{"local date ahead of UTC", time.Date(2024, 3, 9, 20, 0, 0, 0, time.UTC), 0, tokyo,
+			time.Date(2024, 3, 10, 0, 0, 0, 0, tokyo)},
+		{"local date behind UTC", time.Date(2024, 3, 10, 5, 0, 0, 0, time.UTC), 0, honolulu,
+			time.Date(2024, 3, 9, 0, 0, 0, 0, honolulu)},
+		{"month boundary", time.Date(2024, 3, 1, 3, 0, 0, 0, tokyo), 1, tokyo,
+			time.Date(2024, 2, 29, 0, 0, 0, 0, tokyo)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			now = func() time.Time { return tt.now }
+			if got := Cutoff(tt.days, tt.loc); !got.Equal(tt.want) {
+				t.Errorf("Cutoff(%d, %v) = %v, want %v", tt.days, tt.loc, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCutoffAcrossDST(t *testing.T) {
+	defer func(clock func() time.Time) { now = clock }(now)
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database unavailable:", err)
+	}
+	// DST started on 2024-03-10; the cutoff is still local midnight (EST)
+	now = func() time.Time { return time.Date(2024, 3, 11, 12, 0, 0, 0, ny) }
+	want := time.Date(2024, 3, 9, 0, 0, 0, 0, ny)
+	if got := Cutoff(2, ny); !got.Equal(want) {
+		t.Errorf("Cutoff(2, %v) = %v, want %v", ny, got, want)
+	}
+}
+
+func TestDaysRetentionPolicyTimezone(t *testing.T) {
+	defer func(clock func() time.Time) { now = clock }(now)
+
+	tokyo := time.FixedZone("UTC+9", 9*60*60)
+	now = func() time.Time { return time.Date(2024, 3, 10, 1, 30, 0, 0, time.UTC) }
+	p := NewDaysRetentionPolicy(1, api.AgeFieldLastUpdated).WithTimezone(tokyo)
+
+	for _, tt := range []struct {
+		updated time.Time
+		want    bool
+	}{
+		{time.Date(2024, 3, 9, 0, 0, 1, 0, tokyo), true},
+		{time.Date(2024, 3, 9, 0, 0, 0, 0, tokyo), false},
+		// More than 24 hours ago, but after local midnight of the previous day
+		{time.Date(2024, 3, 9, 5, 0, 0, 0, tokyo), true},
+		{time.Date(2024, 3, 8, 23, 0, 0, 0, tokyo), false},
+	} {
+		if got := p.ShouldKeep(api.Tag{Name: "t", LastUpdated: tt.updated}); got != tt.want {
+			t.Errorf("ShouldKeep(updated %v) = %v, want %v", tt.updated, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package policy
+
+import (
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// DigestPinnedPolicy keeps tags whose manifest is pinned by digest
+// (image@sha256:...) somewhere, matching the tag's digest or the digest of
+// any of its platform images
+type DigestPinnedPolicy struct {
+	digests map[string]bool
+	keepAll bool
+}
+
+// NewDigestPinnedPolicy creates a new digest-pinned policy for the given digests
+func NewDigestPinnedPolicy(digests []string) *DigestPinnedPolicy {
+	set := make(map[string]bool, len(digests))
+	for _, d := range digests {
+		if d != "" {
+			set[d] = true
+		}
+	}
+
+	return &DigestPinnedPolicy{
+		digests: set,
+	}
+}
+
+// NewUnavailableDigestPolicy creates a digest-pinned policy that keeps every
+// tag, for when the list of pinned digests could not be loaded (fail closed)
+func NewUnavailableDigestPolicy() *DigestPinnedPolicy {
+	return &DigestPinnedPolicy{
+		keepAll: true,
+	}
+}
+
+// ShouldKeep returns true if the tag's manifest or one of its images is pinned
+func (p *DigestPinnedPolicy) ShouldKeep(tag api.Tag) bool {
+	if p.keepAll || p.digests[tag.Digest] {
+		return true
+	}
+	for _, img := range tag.Images {
+		if p.digests[img.Digest] {
+			return true
+		}
+	}
+	return false
+}
+
+// Name returns the policy name
+func (p *DigestPinnedPolicy) Name() string {
+	return "digest-pinned"
+}
@@ -0,0 +1,116 @@
+package policy
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenNow is the clock for days-based policies, so golden files do not age
+var goldenNow = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// version maps a tag name to a semver string the way the semver sorter does
+// without a strip prefix
+func version(name string) string {
+	return "v" + strings.TrimPrefix(name, "v")
+}
+
+// goldenPolicies are built over every fixture, whose tags must be listed in
+// sort order; each writes testdata/<fixture>.<name>.golden
+func goldenPolicies(t *testing.T, sorted []api.Tag) map[string]RetentionPolicy {
+	t.Helper()
+	pattern, err := NewPatternKeepPolicy(`^(latest|main)$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]RetentionPolicy{
+		"count-3":          NewCountRetentionPolicy(3, sorted),
+		"days-30":          NewDaysRetentionPolicy(30, api.AgeFieldLastUpdated),
+		"hybrid-2-30":      NewHybridRetentionPolicy(2, 30, api.AgeFieldLastUpdated, nil, sorted),
+		"keep-pattern":     pattern,
+		"highest-semver":   NewHighestSemverPolicy(version, sorted),
+		"per-major-1":      NewSemverSeriesPolicy(1, SeriesMajor, version, sorted),
+		"stable-only":      NewPrereleasePolicy(version),
+		"prereleases-only": NewPrereleasePolicy(version).KeepPrereleases(),
+		"size-budget-300":  NewSizeRetentionPolicy(300, sorted),
+	}
+}
+
+// TestGoldenPolicy builds each policy over each testdata/*.json fixture
+// (see api.ReadTags) and compares the kept tags against its golden file
+// Run with -update after an intended change to regenerate the golden files
+func TestGoldenPolicy(t *testing.T) {
+	defer func(clock func() time.Time) { now = clock }(now)
+	now = func() time.Time { return goldenNow }
+
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatal("no fixtures in testdata")
+	}
+
+	for _, fixture := range fixtures {
+		tags := readFixture(t, fixture)
+		for name, p := range goldenPolicies(t, tags) {
+			golden := strings.TrimSuffix(fixture, ".json") + "." + name + ".golden"
+			t.Run(filepath.Base(golden), func(t *testing.T) {
+				compareGolden(t, golden, keptNames(p, tags))
+			})
+		}
+	}
+}
+
+// readFixture loads the tags of a JSON fixture
+func readFixture(t *testing.T, path string) []api.Tag {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tags, err := api.ReadTags(f)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return tags
+}
+
+// keptNames renders the names of the tags the policy keeps, one per line
+func keptNames(p RetentionPolicy, tags []api.Tag) []byte {
+	var b bytes.Buffer
+	for _, tag := range tags {
+		if p.ShouldKeep(tag) {
+			b.WriteString(tag.Name)
+			b.WriteByte('\n')
+		}
+	}
+	return b.Bytes()
+}
+
+// compareGolden compares got with the golden file, rewriting it with -update
+func compareGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s mismatch (run with -update to accept)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
@@ -0,0 +1,68 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// GroupedCountPolicy keeps the last X tags within each group
+// Groups are derived from the tag name using a regex
+type GroupedCountPolicy struct {
+	keepSet map[string]bool
+}
+
+// NewGroupedCountPolicy creates a new grouped count policy
+// The group key is the first capture group of pattern (or the whole match if
+// the pattern has no groups). Tags not matching pattern share a default group.
+// The optional view function rewrites tag names before extracting the key.
+// The sorted parameter should contain tags already sorted in the desired order
+func NewGroupedCountPolicy(count int, pattern string, view func(name string) string, sorted []api.Tag) (*GroupedCountPolicy, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile group pattern: %w", err)
+	}
+
+	keepSet := make(map[string]bool)
+	seen := make(map[string]int)
+
+	for _, tag := range sorted {
+		name := tag.Name
+		if view != nil {
+			name = view(name)
+		}
+		key := GroupKey(re, name)
+		if seen[key] < count {
+			keepSet[tag.Name] = true
+			seen[key]++
+		}
+	}
+
+	return &GroupedCountPolicy{
+		keepSet: keepSet,
+	}, nil
+}
+
+// GroupKey extracts the group key for a tag name
+// Returns an empty string (the default group) if the tag does not match
+func GroupKey(re *regexp.Regexp, name string) string {
+	m := re.FindStringSubmatch(name)
+	if m == nil {
+		return ""
+	}
+	if len(m) > 1 {
+		return m[1]
+	}
+	return m[0]
+}
+
+// ShouldKeep returns true if the tag is in the keep set
+func (p *GroupedCountPolicy) ShouldKeep(tag api.Tag) bool {
+	return p.keepSet[tag.Name]
+}
+
+// Name returns the policy name
+func (p *GroupedCountPolicy) Name() string {
+	return "grouped-count"
+}
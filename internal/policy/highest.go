@@ -0,0 +1,49 @@
+package policy
+
+import (
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+	"golang.org/x/mod/semver"
+)
+
+// HighestSemverPolicy keeps the current release: the stable semver tag of
+// highest precedence, however old it is. Tags of equal precedence (such as
+// "1.2.3" and "v1.2.3") are all kept. Nothing is kept if no tag is a valid
+// stable version.
+type HighestSemverPolicy struct {
+	keepSet map[string]bool
+}
+
+// NewHighestSemverPolicy creates a new highest semver policy
+// The version function maps a tag name to a "v"-prefixed semver string
+func NewHighestSemverPolicy(version func(name string) string, tags []api.Tag) *HighestSemverPolicy {
+	var highest string
+	for _, tag := range tags {
+		v := version(tag.Name)
+		if semver.IsValid(v) && semver.Prerelease(v) == "" && (highest == "" || semver.Compare(v, highest) > 0) {
+			highest = v
+		}
+	}
+
+	keepSet := make(map[string]bool)
+	if highest != "" {
+		for _, tag := range tags {
+			if v := version(tag.Name); semver.IsValid(v) && semver.Compare(v, highest) == 0 {
+				keepSet[tag.Name] = true
+			}
+		}
+	}
+
+	return &HighestSemverPolicy{
+		keepSet: keepSet,
+	}
+}
+
+// ShouldKeep returns true if the tag is the highest release
+func (p *HighestSemverPolicy) ShouldKeep(tag api.Tag) bool {
+	return p.keepSet[tag.Name]
+}
+
+// Name returns the policy name
+func (p *HighestSemverPolicy) Name() string {
+	return "highest-semver"
+}
@@ -0,0 +1,77 @@
+package policy
+
+import (
+	"time"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// HybridRetentionPolicy keeps the last X tags plus every tag newer than Y days
+// The keep set is computed once from a single cutoff, so both rules always
+// agree on which tags they cover
+type HybridRetentionPolicy struct {
+	keepSet map[string]bool
+	count   map[string]bool // kept as one of the last X tags
+	days    map[string]bool // kept as newer than Y days
+}
+
+// NewHybridRetentionPolicy creates a new hybrid count/days retention policy
+// The field selects which tag timestamp is compared against the cutoff, and
+// loc its day boundaries (nil for a rolling cutoff, see Cutoff).
+// The sorted parameter should contain tags already sorted in the desired order
+func NewHybridRetentionPolicy(count, days int, field api.AgeField, loc *time.Location, sorted []api.Tag) *HybridRetentionPolicy {
+	p := &HybridRetentionPolicy{
+		keepSet: make(map[string]bool),
+		count:   make(map[string]bool),
+		days:    make(map[string]bool),
+	}
+	cutoff := Cutoff(days, loc)
+
+	for i, tag := range sorted {
+		if i < count {
+			p.count[tag.Name] = true
+			p.keepSet[tag.Name] = true
+		}
+		if tag.Time(field).After(cutoff) {
+			p.days[tag.Name] = true
+			p.keepSet[tag.Name] = true
+		}
+	}
+
+	return p
+}
+
+// ShouldKeep returns true if the tag is in the keep set
+func (p *HybridRetentionPolicy) ShouldKeep(tag api.Tag) bool {
+	return p.keepSet[tag.Name]
+}
+
+// Name returns the policy name
+func (p *HybridRetentionPolicy) Name() string {
+	return "hybrid"
+}
+
+// Policies returns the count and days decisions combined by this policy,
+// so each can be reported separately
+func (p *HybridRetentionPolicy) Policies() []RetentionPolicy {
+	return []RetentionPolicy{
+		&keepSetPolicy{name: "count", keepSet: p.count},
+		&keepSetPolicy{name: "days", keepSet: p.days},
+	}
+}
+
+// keepSetPolicy keeps a precomputed set of tags
+type keepSetPolicy struct {
+	name    string
+	keepSet map[string]bool
+}
+
+// ShouldKeep returns true if the tag is in the keep set
+func (p *keepSetPolicy) ShouldKeep(tag api.Tag) bool {
+	return p.keepSet[tag.Name]
+}
+
+// Name returns the policy name
+func (p *keepSetPolicy) Name() string {
+	return p.name
+}
@@ -0,0 +1,32 @@
+package policy
+
+import (
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// InUsePolicy keeps tags reported as deployed by an external source
+type InUsePolicy struct {
+	tags map[string]bool
+}
+
+// NewInUsePolicy creates a new in-use policy for the given tag names
+func NewInUsePolicy(tags []string) *InUsePolicy {
+	set := make(map[string]bool, len(tags))
+	for _, name := range tags {
+		set[name] = true
+	}
+
+	return &InUsePolicy{
+		tags: set,
+	}
+}
+
+// ShouldKeep returns true if the tag is in use
+func (p *InUsePolicy) ShouldKeep(tag api.Tag) bool {
+	return p.tags[tag.Name]
+}
+
+// Name returns the policy name
+func (p *InUsePolicy) Name() string {
+	return "in-use"
+}
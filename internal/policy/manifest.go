@@ -0,0 +1,41 @@
+package policy
+
+import "github.com/ataraskov/docker-hub-cleaner/internal/api"
+
+// ManifestCountPolicy keeps every tag of the last X distinct manifests
+type ManifestCountPolicy struct {
+	keepSet map[string]bool
+}
+
+// NewManifestCountPolicy creates a new manifest count policy
+// The key function identifies the manifest a tag points to; tags sharing a
+// key count once. The sorted parameter should contain tags already sorted
+// in the desired order
+func NewManifestCountPolicy(count int, key func(tag api.Tag) string, sorted []api.Tag) *ManifestCountPolicy {
+	keepSet := make(map[string]bool)
+	kept := make(map[string]bool)
+
+	for _, tag := range sorted {
+		k := key(tag)
+		if !kept[k] && len(kept) < count {
+			kept[k] = true
+		}
+		if kept[k] {
+			keepSet[tag.Name] = true
+		}
+	}
+
+	return &ManifestCountPolicy{
+		keepSet: keepSet,
+	}
+}
+
+// ShouldKeep returns true if the tag is in the keep set
+func (p *ManifestCountPolicy) ShouldKeep(tag api.Tag) bool {
+	return p.keepSet[tag.Name]
+}
+
+// Name returns the policy name
+func (p *ManifestCountPolicy) Name() string {
+	return "manifest-count"
+}
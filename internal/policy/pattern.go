@@ -0,0 +1,63 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// PatternKeepPolicy unconditionally keeps tags matching a regex
+type PatternKeepPolicy struct {
+	pattern *regexp.Regexp
+}
+
+// NewPatternKeepPolicy creates a new pattern keep policy
+func NewPatternKeepPolicy(pattern string) (*PatternKeepPolicy, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile keep pattern: %w", err)
+	}
+
+	return &PatternKeepPolicy{
+		pattern: re,
+	}, nil
+}
+
+// ShouldKeep returns true if the tag matches the pattern
+func (p *PatternKeepPolicy) ShouldKeep(tag api.Tag) bool {
+	return p.pattern.MatchString(tag.Name)
+}
+
+// Name returns the policy name
+func (p *PatternKeepPolicy) Name() string {
+	return "pattern-keep"
+}
+
+// DeletePatternPolicy keeps every tag that does not match a regex, so
+// matching tags are deleted regardless of age or count
+type DeletePatternPolicy struct {
+	pattern *regexp.Regexp
+}
+
+// NewDeletePatternPolicy creates a new delete pattern policy
+func NewDeletePatternPolicy(pattern string) (*DeletePatternPolicy, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile delete pattern: %w", err)
+	}
+
+	return &DeletePatternPolicy{
+		pattern: re,
+	}, nil
+}
+
+// ShouldKeep returns true if the tag does not match the pattern
+func (p *DeletePatternPolicy) ShouldKeep(tag api.Tag) bool {
+	return !p.pattern.MatchString(tag.Name)
+}
+
+// Name returns the policy name
+func (p *DeletePatternPolicy) Name() string {
+	return "delete-pattern"
+}
@@ -9,3 +9,51 @@ type RetentionPolicy interface {
 	// Name returns the name of the policy
 	Name() string
 }
+
+// KeepCount reports how many tags a single policy voted to keep
+type KeepCount struct {
+	Policy string `json:"policy"`
+	Kept   int    `json:"kept"`
+}
+
+// combined is implemented by policies built from other policies
+// (CompositePolicy, HybridRetentionPolicy)
+type combined interface {
+	Policies() []RetentionPolicy
+}
+
+// KeepCounts evaluates each policy individually over tags and reports
+// how many tags it would keep. Combined policies are expanded recursively
+// into their member policies so each one is reported separately.
+func KeepCounts(p RetentionPolicy, tags []api.Tag) []KeepCount {
+	if p == nil {
+		return nil
+	}
+
+	policies := flatten(p)
+
+	counts := make([]KeepCount, 0, len(policies))
+	for _, policy := range policies {
+		kept := 0
+		for _, tag := range tags {
+			if policy.ShouldKeep(tag) {
+				kept++
+			}
+		}
+		counts = append(counts, KeepCount{Policy: policy.Name(), Kept: kept})
+	}
+	return counts
+}
+
+// flatten expands combined policies into the policies they are built from
+func flatten(p RetentionPolicy) []RetentionPolicy {
+	c, ok := p.(combined)
+	if !ok {
+		return []RetentionPolicy{p}
+	}
+	var policies []RetentionPolicy
+	for _, member := range c.Policies() {
+		policies = append(policies, flatten(member)...)
+	}
+	return policies
+}
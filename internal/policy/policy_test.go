@@ -0,0 +1,73 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+func TestKeepCountsExpandsHybridAndNestedComposites(t *testing.T) {
+	now := time.Now()
+	tags := []api.Tag{
+		{Name: "v5", LastUpdated: now.Add(-1 * 24 * time.Hour)},
+		{Name: "v4", LastUpdated: now.Add(-2 * 24 * time.Hour)},
+		{Name: "v3", LastUpdated: now.Add(-3 * 24 * time.Hour)},
+		{Name: "v2", LastUpdated: now.Add(-40 * 24 * time.Hour)},
+		{Name: "v1", LastUpdated: now.Add(-50 * 24 * time.Hour)},
+	}
+
+	hybrid := NewHybridRetentionPolicy(1, 7, api.AgeFieldLastUpdated, nil, tags)
+	// Protection wrappers nest the retention policy one level down
+	p := NewCompositePolicy(PolicyModeOR,
+		NewCompositePolicy(PolicyModeOR, hybrid, NewInUsePolicy([]string{"v1"})),
+		NewInUsePolicy([]string{"v2", "v1"}),
+	)
+
+	got := KeepCounts(p, tags)
+	want := []KeepCount{
+		{Policy: "count", Kept: 1},
+		{Policy: "days", Kept: 3},
+		{Policy: "in-use", Kept: 1},
+		{Policy: "in-use", Kept: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("KeepCounts() = %v, want %v", got, want)
+	}
+}
+
+func TestKeepCountsSinglePolicy(t *testing.T) {
+	tags := []api.Tag{{Name: "a"}, {Name: "b"}}
+
+	got := KeepCounts(NewInUsePolicy([]string{"a"}), tags)
+	want := []KeepCount{{Policy: "in-use", Kept: 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("KeepCounts() = %v, want %v", got, want)
+	}
+}
+
+func TestGroupedCountPolicyLatestPerFamily(t *testing.T) {
+	// Newest first; three families plus tags outside every family
+	sorted := []api.Tag{
+		{Name: "feature-login-12"}, {Name: "feature-search-7"}, {Name: "feature-login-11"},
+		{Name: "main"}, {Name: "feature-cart-3"}, {Name: "release-1.0"},
+		{Name: "feature-search-6"}, {Name: "feature-cart-2"}, {Name: "feature-login-10"},
+	}
+	p, err := NewGroupedCountPolicy(1, `^feature-([a-z]+)-[0-9]+$`, nil, sorted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, tag := range sorted {
+		if p.ShouldKeep(tag) {
+			got = append(got, tag.Name)
+		}
+	}
+	// Non-matching tags share the default group, so only the first is kept
+	want := []string{"feature-login-12", "feature-search-7", "main", "feature-cart-3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("kept = %v, want %v", got, want)
+	}
+}
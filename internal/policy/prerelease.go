@@ -0,0 +1,105 @@
+package policy
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+	"golang.org/x/mod/semver"
+)
+
+// PrereleasePolicy keeps stable releases and rejects semver prereleases
+// (e.g. 1.2.3-rc1). Tags that are not valid semver are kept.
+// With KeepPrereleases it does the opposite and keeps only the prereleases.
+type PrereleasePolicy struct {
+	version func(name string) string
+	keep    bool
+}
+
+// NewPrereleasePolicy creates a new prerelease policy
+// The version function maps a tag name to a "v"-prefixed semver string
+func NewPrereleasePolicy(version func(name string) string) *PrereleasePolicy {
+	return &PrereleasePolicy{
+		version: version,
+	}
+}
+
+// KeepPrereleases switches the policy to keeping semver prereleases and
+// rejecting every other tag, for use as a keep rule ORed with the others
+func (p *PrereleasePolicy) KeepPrereleases() *PrereleasePolicy {
+	p.keep = true
+	return p
+}
+
+// ShouldKeep returns false if the tag is a semver prerelease (or, with
+// KeepPrereleases, if it is not)
+func (p *PrereleasePolicy) ShouldKeep(tag api.Tag) bool {
+	v := p.version(tag.Name)
+	if !semver.IsValid(v) {
+		return !p.keep
+	}
+	return (semver.Prerelease(v) != "") == p.keep
+}
+
+// Name returns the policy name
+func (p *PrereleasePolicy) Name() string {
+	if p.keep {
+		return "keep-prerelease"
+	}
+	return "prerelease"
+}
+
+// ReleasedPrereleasePolicy rejects semver prereleases whose stable release
+// (e.g. 1.2.3 for 1.2.3-rc1) was published before a cutoff, and keeps every
+// other tag
+type ReleasedPrereleasePolicy struct {
+	version  func(name string) string
+	released map[string]time.Time // release version -> earliest stable tag time
+	days     int
+	loc      *time.Location // optional: day boundaries in this zone (see Cutoff)
+}
+
+// NewReleasedPrereleasePolicy creates a new released prerelease policy
+// Release times are taken from the stable tags among tags, using field.
+// The version function maps a tag name to a "v"-prefixed semver string
+func NewReleasedPrereleasePolicy(days int, field api.AgeField, version func(name string) string, tags []api.Tag) *ReleasedPrereleasePolicy {
+	released := make(map[string]time.Time)
+	for _, tag := range tags {
+		v := version(tag.Name)
+		if !semver.IsValid(v) || semver.Prerelease(v) != "" {
+			continue
+		}
+		key := semver.Canonical(v)
+		if t, ok := released[key]; !ok || tag.Time(field).Before(t) {
+			released[key] = tag.Time(field)
+		}
+	}
+
+	return &ReleasedPrereleasePolicy{
+		version:  version,
+		released: released,
+		days:     days,
+	}
+}
+
+// WithTimezone counts days from midnight in loc instead of a rolling cutoff
+func (p *ReleasedPrereleasePolicy) WithTimezone(loc *time.Location) *ReleasedPrereleasePolicy {
+	p.loc = loc
+	return p
+}
+
+// ShouldKeep returns false if the tag is a prerelease of a version released
+// more than the configured number of days ago
+func (p *ReleasedPrereleasePolicy) ShouldKeep(tag api.Tag) bool {
+	v := p.version(tag.Name)
+	if !semver.IsValid(v) || semver.Prerelease(v) == "" {
+		return true
+	}
+	t, ok := p.released[strings.TrimSuffix(semver.Canonical(v), semver.Prerelease(v))]
+	return !ok || !t.Before(Cutoff(p.days, p.loc))
+}
+
+// Name returns the policy name
+func (p *ReleasedPrereleasePolicy) Name() string {
+	return "released-prerelease"
+}
@@ -0,0 +1,58 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// tagName matches a valid Docker tag, which is protected by exact name
+var tagName = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+
+// ProtectPolicy keeps tags on a protected list
+// Entries that are valid tag names match exactly ("latest" does not protect
+// "latest-dev"); any other entry is a regex ("^release-.*")
+type ProtectPolicy struct {
+	names    map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// NewProtectPolicy creates a new protect policy for the given entries
+func NewProtectPolicy(entries []string) (*ProtectPolicy, error) {
+	p := &ProtectPolicy{
+		names: make(map[string]bool),
+	}
+
+	for _, entry := range entries {
+		if tagName.MatchString(entry) {
+			p.names[entry] = true
+			continue
+		}
+		re, err := regexp.Compile(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile protect pattern %q: %w", entry, err)
+		}
+		p.patterns = append(p.patterns, re)
+	}
+
+	return p, nil
+}
+
+// ShouldKeep returns true if the tag is protected
+func (p *ProtectPolicy) ShouldKeep(tag api.Tag) bool {
+	if p.names[tag.Name] {
+		return true
+	}
+	for _, re := range p.patterns {
+		if re.MatchString(tag.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Name returns the policy name
+func (p *ProtectPolicy) Name() string {
+	return "protect"
+}
@@ -0,0 +1,43 @@
+package policy
+
+import (
+	"time"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// LastPulledRetentionPolicy keeps tags pulled within X days
+type LastPulledRetentionPolicy struct {
+	days        int
+	keepUnknown bool           // keep tags without a last-pulled time
+	loc         *time.Location // optional: day boundaries in this zone (see Cutoff)
+}
+
+// NewLastPulledRetentionPolicy creates a new last-pulled retention policy
+// Docker Hub omits tag_last_pulled for tags never pulled (and on some plans
+// entirely); keepUnknown decides whether such tags are kept
+func NewLastPulledRetentionPolicy(days int, keepUnknown bool) *LastPulledRetentionPolicy {
+	return &LastPulledRetentionPolicy{
+		days:        days,
+		keepUnknown: keepUnknown,
+	}
+}
+
+// WithTimezone counts days from midnight in loc instead of a rolling cutoff
+func (p *LastPulledRetentionPolicy) WithTimezone(loc *time.Location) *LastPulledRetentionPolicy {
+	p.loc = loc
+	return p
+}
+
+// ShouldKeep returns true if the tag was pulled within the retention period
+func (p *LastPulledRetentionPolicy) ShouldKeep(tag api.Tag) bool {
+	if tag.TagLastPulled.IsZero() {
+		return p.keepUnknown
+	}
+	return tag.TagLastPulled.After(Cutoff(p.days, p.loc))
+}
+
+// Name returns the policy name
+func (p *LastPulledRetentionPolicy) Name() string {
+	return "last-pulled"
+}
@@ -0,0 +1,53 @@
+package policy
+
+import (
+	"regexp"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// Rule applies its own retention policy to the tags matching a pattern
+type Rule struct {
+	Pattern *regexp.Regexp
+	Policy  RetentionPolicy
+}
+
+// RulesPolicy evaluates each tag with the policy of the first rule whose
+// pattern matches it. Tags matching no rule are evaluated by the fallback
+// policy, or kept if there is none
+type RulesPolicy struct {
+	rules    []Rule
+	fallback RetentionPolicy
+}
+
+// NewRulesPolicy creates a new rules policy
+func NewRulesPolicy(rules []Rule, fallback RetentionPolicy) *RulesPolicy {
+	return &RulesPolicy{
+		rules:    rules,
+		fallback: fallback,
+	}
+}
+
+// ShouldKeep returns the decision of the first matching rule's policy
+func (p *RulesPolicy) ShouldKeep(tag api.Tag) bool {
+	if i := MatchRule(p.rules, tag.Name); i >= 0 {
+		return p.rules[i].Policy.ShouldKeep(tag)
+	}
+	return p.fallback == nil || p.fallback.ShouldKeep(tag)
+}
+
+// Name returns the policy name
+func (p *RulesPolicy) Name() string {
+	return "rules"
+}
+
+// MatchRule returns the index of the first rule whose pattern matches the
+// tag name, or -1 if none does
+func MatchRule(rules []Rule, name string) int {
+	for i, r := range rules {
+		if r.Pattern.MatchString(name) {
+			return i
+		}
+	}
+	return -1
+}
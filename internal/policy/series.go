@@ -0,0 +1,61 @@
+package policy
+
+import (
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+	"golang.org/x/mod/semver"
+)
+
+// Series selects how semver tags are grouped into release series
+type Series string
+
+// Release series
+const (
+	SeriesMajor Series = "major" // 1.x.y
+	SeriesMinor Series = "minor" // 1.2.x
+)
+
+// SemverSeriesPolicy keeps the newest X tags within each release series
+// (major or major.minor), so older maintenance lines keep their latest
+// builds. Tags that are not valid semver are not kept by this policy.
+type SemverSeriesPolicy struct {
+	series  Series
+	keepSet map[string]bool
+}
+
+// NewSemverSeriesPolicy creates a new per-series count policy
+// The version function maps a tag name to a "v"-prefixed semver string.
+// The sorted parameter should contain tags already sorted in the desired order
+func NewSemverSeriesPolicy(count int, series Series, version func(name string) string, sorted []api.Tag) *SemverSeriesPolicy {
+	keepSet := make(map[string]bool)
+	seen := make(map[string]int)
+
+	for _, tag := range sorted {
+		v := version(tag.Name)
+		if !semver.IsValid(v) {
+			continue
+		}
+		key := semver.Major(v)
+		if series == SeriesMinor {
+			key = semver.MajorMinor(v)
+		}
+		if seen[key] < count {
+			keepSet[tag.Name] = true
+			seen[key]++
+		}
+	}
+
+	return &SemverSeriesPolicy{
+		series:  series,
+		keepSet: keepSet,
+	}
+}
+
+// ShouldKeep returns true if the tag is among the newest of its series
+func (p *SemverSeriesPolicy) ShouldKeep(tag api.Tag) bool {
+	return p.keepSet[tag.Name]
+}
+
+// Name returns the policy name
+func (p *SemverSeriesPolicy) Name() string {
+	return "per-" + string(p.series)
+}
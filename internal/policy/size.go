@@ -0,0 +1,112 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// SizeFloorCountPolicy keeps the last X tags unconditionally, then older tags
+// only while their combined size stays within a byte budget
+// The count is a floor: it is never reduced to meet the budget
+type SizeFloorCountPolicy struct {
+	keepSet map[string]bool
+}
+
+// NewSizeFloorCountPolicy creates a new size budget policy with a count floor
+// Sizes are counted once per digest, so tags of the same image do not
+// exhaust the budget twice.
+// The sorted parameter should contain tags already sorted in the desired order
+func NewSizeFloorCountPolicy(count int, budget int64, sorted []api.Tag) *SizeFloorCountPolicy {
+	return &SizeFloorCountPolicy{
+		keepSet: keepWithinBudget(count, budget, sorted),
+	}
+}
+
+// ShouldKeep returns true if the tag is in the keep set
+func (p *SizeFloorCountPolicy) ShouldKeep(tag api.Tag) bool {
+	return p.keepSet[tag.Name]
+}
+
+// Name returns the policy name
+func (p *SizeFloorCountPolicy) Name() string {
+	return "size-floor"
+}
+
+// SizeRetentionPolicy keeps the newest tags while their combined size stays
+// within a byte budget; the first tag over budget and every older tag are
+// deletion candidates
+type SizeRetentionPolicy struct {
+	keepSet map[string]bool
+}
+
+// NewSizeRetentionPolicy creates a new size retention policy
+// Sizes are counted once per digest, as in NewSizeFloorCountPolicy.
+// The sorted parameter should contain tags already sorted in the desired order
+func NewSizeRetentionPolicy(budget int64, sorted []api.Tag) *SizeRetentionPolicy {
+	return &SizeRetentionPolicy{
+		keepSet: keepWithinBudget(0, budget, sorted),
+	}
+}
+
+// ShouldKeep returns true if the tag is in the keep set
+func (p *SizeRetentionPolicy) ShouldKeep(tag api.Tag) bool {
+	return p.keepSet[tag.Name]
+}
+
+// Name returns the policy name
+func (p *SizeRetentionPolicy) Name() string {
+	return "size"
+}
+
+// keepWithinBudget returns the first count sorted tags, followed by the next
+// tags while their combined size (once per digest) stays within budget
+func keepWithinBudget(count int, budget int64, sorted []api.Tag) map[string]bool {
+	keepSet := make(map[string]bool)
+	counted := make(map[string]bool)
+	var total int64
+
+	for i, tag := range sorted {
+		size := tag.FullSize
+		if tag.Digest != "" && counted[tag.Digest] {
+			size = 0
+		}
+		// Stop at the first tag over budget, so the kept tags stay the newest
+		if i >= count && total+size > budget {
+			break
+		}
+		keepSet[tag.Name] = true
+		total += size
+		if tag.Digest != "" {
+			counted[tag.Digest] = true
+		}
+	}
+
+	return keepSet
+}
+
+// sizePattern matches a size with an optional binary unit, e.g. 50GB or 1.5GiB
+var sizePattern = regexp.MustCompile(`(?i)^([0-9]+(?:\.[0-9]+)?)\s*([KMGT]?)(?:I?B)?$`)
+
+// ParseSize parses a size such as "50GB", "512MiB" or "1073741824"
+// Units are binary (1 GB = 1024 MB), as in the run summary
+func ParseSize(s string) (int64, error) {
+	m := sizePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("invalid size %q (e.g., 50GB, 512MB)", s)
+	}
+
+	v, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q (e.g., 50GB, 512MB)", s)
+	}
+	if m[2] != "" {
+		for range strings.Index("KMGT", strings.ToUpper(m[2])) + 1 {
+			v *= 1024
+		}
+	}
+	return int64(v), nil
+}
@@ -0,0 +1,120 @@
+package reference
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// repositoryName matches a Docker Hub namespace/repository pair
+var repositoryName = regexp.MustCompile(`^[a-z0-9]+(?:[._-][a-z0-9]+)*/[a-z0-9]+(?:[._-][a-z0-9]+)*$`)
+
+// Repository turns the accepted spellings of a Docker Hub repository into
+// namespace/repo: a leading URL scheme, Docker Hub host or hub.docker.com
+// URL is stripped and official images ("nginx") expand to "library/nginx"
+func Repository(s string) (string, error) {
+	repo := strings.TrimSuffix(strings.TrimSpace(s), "/")
+	repo = strings.TrimPrefix(repo, "https://")
+	repo = strings.TrimPrefix(repo, "http://")
+
+	if host, rest, ok := strings.Cut(repo, "/"); ok && isHost(host) {
+		switch host {
+		case "docker.io", "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+			repo = rest
+		case "hub.docker.com":
+			// Web URLs: hub.docker.com/r/<namespace>/<repo> or hub.docker.com/_/<name>
+			repo = strings.TrimPrefix(rest, "r/")
+			if name, ok := strings.CutPrefix(rest, "_/"); ok {
+				repo = "library/" + name
+			}
+		case "ghcr.io":
+			return "", fmt.Errorf("invalid repository %q: use --registry ghcr for GitHub Container Registry images", s)
+		default:
+			return "", fmt.Errorf("invalid repository %q: only Docker Hub repositories are supported, not %s", s, host)
+		}
+	}
+
+	if strings.ContainsAny(repo, ":@") {
+		return "", fmt.Errorf("invalid repository %q: remove the tag or digest", s)
+	}
+	if !strings.Contains(repo, "/") {
+		repo = "library/" + repo
+	}
+	if !repositoryName.MatchString(repo) {
+		return "", fmt.Errorf("invalid repository %q: expected namespace/repo (lowercase letters, digits, '.', '_' or '-')", s)
+	}
+	return repo, nil
+}
+
+// isHost reports whether the first path segment of a reference is a
+// registry host rather than a namespace
+func isHost(segment string) bool {
+	return strings.ContainsAny(segment, ".:") || segment == "localhost"
+}
+
+// Ref is a parsed image reference: name[:tag][@digest]
+type Ref struct {
+	Name   string // repository as written, possibly with a registry host
+	Tag    string // empty if the reference has none
+	Digest string // empty if the reference has none
+}
+
+var (
+	// refName matches a repository name with an optional registry host
+	refName = regexp.MustCompile(`^(?:[a-zA-Z0-9.-]+(?::[0-9]+)?/)?[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	// refTag matches a tag name
+	refTag = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+	// refDigest matches a content digest (algorithm:hex), which a
+	// repo:tag reference such as nginx:latest never does
+	refDigest = regexp.MustCompile(`^[a-z0-9]+(?:[+._-][a-z0-9]+)*:[a-f0-9]{32,}$`)
+)
+
+// IsDigest reports whether s is a content digest such as sha256:...
+func IsDigest(s string) bool {
+	return refDigest.MatchString(s)
+}
+
+// Parse splits an image reference such as docker.io/org/app:1.2,
+// nginx:1.2 or app@sha256:... into its parts; the name is required
+func Parse(s string) (Ref, error) {
+	var ref Ref
+	rest := strings.TrimSpace(s)
+
+	if name, digest, ok := strings.Cut(rest, "@"); ok {
+		if !IsDigest(digest) {
+			return Ref{}, fmt.Errorf("invalid image reference %q: bad digest %q", s, digest)
+		}
+		rest, ref.Digest = name, digest
+	}
+
+	// The tag follows the last colon after the last slash (a colon before
+	// it separates a registry port)
+	if i := strings.LastIndex(rest, ":"); i > strings.LastIndex(rest, "/") {
+		rest, ref.Tag = rest[:i], rest[i+1:]
+		if !refTag.MatchString(ref.Tag) {
+			return Ref{}, fmt.Errorf("invalid image reference %q: bad tag %q", s, ref.Tag)
+		}
+	}
+
+	if !refName.MatchString(rest) {
+		return Ref{}, fmt.Errorf("invalid image reference %q: bad repository name %q", s, rest)
+	}
+	ref.Name = rest
+	return ref, nil
+}
+
+// SameRepository reports whether a reference name denotes repo, the
+// normalized repository being cleaned: Docker Hub spellings (docker.io/...,
+// library images) are normalized and other registry hosts stripped
+func SameRepository(name, repo string) bool {
+	if name == repo {
+		return true
+	}
+	if normalized, err := Repository(name); err == nil {
+		return normalized == repo
+	}
+	if host, rest, ok := strings.Cut(name, "/"); ok && isHost(host) {
+		return rest == repo
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package reference
+
+import "testing"
+
+func TestRepository(t *testing.T) {
+	for _, tt := range []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "org/app", want: "org/app"},
+		{in: " org/app/ ", want: "org/app"},
+		{in: "docker.io/org/app", want: "org/app"},
+		{in: "index.docker.io/org/app", want: "org/app"},
+		{in: "registry-1.docker.io/org/app", want: "org/app"},
+		{in: "https://hub.docker.com/r/org/app", want: "org/app"},
+		{in: "hub.docker.com/r/org/app/", want: "org/app"},
+		{in: "https://hub.docker.com/_/nginx", want: "library/nginx"},
+		{in: "nginx", want: "library/nginx"},
+		{in: "docker.io/nginx", want: "library/nginx"},
+		{in: "docker.io/library/nginx", want: "library/nginx"},
+		{in: "org/my-app.v2_x", want: "org/my-app.v2_x"},
+
+		{in: "org/app:1.2", wantErr: true},
+		{in: "docker.io/org/app:latest", wantErr: true},
+		{in: "nginx:latest", wantErr: true},
+		{in: "org/app@sha256:0123", wantErr: true},
+		{in: "ghcr.io/org/app", wantErr: true},
+		{in: "quay.io/org/app", wantErr: true},
+		{in: "localhost/org/app", wantErr: true},
+		{in: "Org/App", wantErr: true},
+		{in: "org/app/extra", wantErr: true},
+		{in: "", wantErr: true},
+	} {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Repository(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Repository(%q) = %q, %v, want error %v", tt.in, got, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Repository(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
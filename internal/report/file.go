@@ -0,0 +1,79 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// File is an output file written through a temporary file next to it and
+// renamed into place by Commit, so readers never see a partial file while
+// the writer can still stream into it
+type File struct {
+	tmp  *os.File
+	path string
+	done bool
+}
+
+// CreateFile starts writing path, creating parent directories as needed
+func CreateFile(path string) (*File, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	return &File{tmp: tmp, path: path}, nil
+}
+
+// Write implements io.Writer
+func (f *File) Write(p []byte) (int, error) {
+	return f.tmp.Write(p)
+}
+
+// Commit moves the written data into place; later calls do nothing
+func (f *File) Commit() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+	defer os.Remove(f.tmp.Name())
+
+	if err := f.tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", f.path, err)
+	}
+	if err := os.Chmod(f.tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("failed to set permissions of %s: %w", f.path, err)
+	}
+	if err := os.Rename(f.tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to rename %s: %w", f.path, err)
+	}
+	return nil
+}
+
+// Abort discards the written data, leaving path untouched
+func (f *File) Abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+	f.tmp.Close()
+	os.Remove(f.tmp.Name())
+}
+
+// WriteFile writes data to path atomically (temp file + rename), creating
+// parent directories as needed, so readers never see a partial file
+func WriteFile(path string, data []byte) error {
+	f, err := CreateFile(path)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Abort()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Commit()
+}
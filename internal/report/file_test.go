@@ -0,0 +1,63 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileCommitsStreamedOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "out.jsonl")
+
+	f, err := CreateFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"{\"tag\":\"a\"}\n", "{\"tag\":\"b\"}\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("file visible before Commit: %v", err)
+	}
+
+	if err := f.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Commit(); err != nil {
+		t.Errorf("second Commit() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "{\"tag\":\"a\"}\n{\"tag\":\"b\"}\n"; got != want {
+		t.Errorf("file = %q, want %q", got, want)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the output file", len(entries))
+	}
+}
+
+func TestFileAbortLeavesPathUntouched(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.json")
+	if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := CreateFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("new"))
+	f.Abort()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "old" {
+		t.Errorf("file = %q, want %q", data, "old")
+	}
+}
@@ -0,0 +1,105 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/cleaner"
+)
+
+// GitHub Actions file commands, see
+// https://docs.github.com/actions/using-workflows/workflow-commands-for-github-actions
+const (
+	GitHubOutputEnv  = "GITHUB_OUTPUT"
+	GitHubSummaryEnv = "GITHUB_STEP_SUMMARY"
+)
+
+// GitHubOutputs renders step outputs as key=value lines, totalled over all
+// reports: deleted_count, reclaimed_bytes, error_count and repository
+// (comma-separated for several repositories), plus failed_repositories
+// (comma-separated) and status: failure if a repository failed or a
+// deletion errored, success otherwise
+func GitHubOutputs(failed []string, reports ...*Report) string {
+	var deleted, errCount int
+	var reclaimed int64
+	var repos []string
+	for _, r := range reports {
+		deleted += len(r.DeletedTags)
+		errCount += len(r.Errors)
+		reclaimed += r.ReclaimedSize
+		repos = append(repos, r.Repository)
+	}
+
+	status := "success"
+	if len(failed) > 0 || errCount > 0 {
+		status = "failure"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "repository=%s\n", strings.Join(repos, ","))
+	fmt.Fprintf(&b, "deleted_count=%d\n", deleted)
+	fmt.Fprintf(&b, "reclaimed_bytes=%d\n", reclaimed)
+	fmt.Fprintf(&b, "error_count=%d\n", errCount)
+	fmt.Fprintf(&b, "failed_repositories=%s\n", strings.Join(failed, ","))
+	fmt.Fprintf(&b, "status=%s\n", status)
+	return b.String()
+}
+
+// GitHubSummary renders a Markdown table with one row per repository,
+// failed repositories last
+func GitHubSummary(dryRun bool, failed []string, reports ...*Report) string {
+	var b strings.Builder
+
+	title := "Docker Hub cleanup"
+	if dryRun {
+		title += " (dry run)"
+	}
+	fmt.Fprintf(&b, "### %s\n\n", title)
+	fmt.Fprintln(&b, "| Repository | Tags | Kept | Deleted | Reclaimed | Errors |")
+	fmt.Fprintln(&b, "|---|---:|---:|---:|---:|---:|")
+	for _, r := range reports {
+		fmt.Fprintf(&b, "| `%s` | %d | %d | %d | %s | %d |\n", r.Repository,
+			r.TotalTags, r.KeptTags, len(r.DeletedTags), cleaner.FormatSize(r.ReclaimedSize), len(r.Errors))
+	}
+	for _, repo := range failed {
+		fmt.Fprintf(&b, "| `%s` | failed | | | | |\n", repo)
+	}
+	return b.String()
+}
+
+// WriteGitHub appends outputs and the step summary to the files named by
+// the GitHub Actions environment; failed names repositories that could not
+// be cleaned, which have no report
+// It returns false if neither variable is set (not running in Actions)
+func WriteGitHub(dryRun bool, failed []string, reports ...*Report) (bool, error) {
+	outputPath, summaryPath := os.Getenv(GitHubOutputEnv), os.Getenv(GitHubSummaryEnv)
+	if outputPath == "" && summaryPath == "" {
+		return false, nil
+	}
+
+	if outputPath != "" {
+		if err := appendFile(outputPath, GitHubOutputs(failed, reports...)); err != nil {
+			return true, fmt.Errorf("failed to write %s: %w", GitHubOutputEnv, err)
+		}
+	}
+	if summaryPath != "" {
+		if err := appendFile(summaryPath, GitHubSummary(dryRun, failed, reports...)); err != nil {
+			return true, fmt.Errorf("failed to write %s: %w", GitHubSummaryEnv, err)
+		}
+	}
+	return true, nil
+}
+
+// appendFile appends data to path; GitHub shares these files between steps
+func appendFile(path, data string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
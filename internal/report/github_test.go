@@ -0,0 +1,60 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteGitHubReportsFailedRepository(t *testing.T) {
+	dir := t.TempDir()
+	outputPath, summaryPath := filepath.Join(dir, "output"), filepath.Join(dir, "summary")
+	t.Setenv(GitHubOutputEnv, outputPath)
+	t.Setenv(GitHubSummaryEnv, summaryPath)
+
+	// A single repository that failed before producing a report
+	ok, err := WriteGitHub(false, []string{"org/app"})
+	if err != nil || !ok {
+		t.Fatalf("WriteGitHub() = %v, %v, want true, nil", ok, err)
+	}
+
+	output, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"failed_repositories=org/app", "status=failure", "deleted_count=0"} {
+		if !strings.Contains(string(output), line+"\n") {
+			t.Errorf("outputs missing %q:\n%s", line, output)
+		}
+	}
+
+	summary, err := os.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(summary), "| `org/app` | failed |") {
+		t.Errorf("summary missing the failed repository:\n%s", summary)
+	}
+}
+
+func TestGitHubOutputs(t *testing.T) {
+	reports := []*Report{
+		{Repository: "org/a", DeletedTags: []string{"v1", "v2"}, ReclaimedSize: 100},
+		{Repository: "org/b", DeletedTags: []string{"v3"}, ReclaimedSize: 50},
+	}
+	want := "repository=org/a,org/b\n" +
+		"deleted_count=3\n" +
+		"reclaimed_bytes=150\n" +
+		"error_count=0\n" +
+		"failed_repositories=\n" +
+		"status=success\n"
+	if got := GitHubOutputs(nil, reports...); got != want {
+		t.Errorf("GitHubOutputs() =\n%s\nwant\n%s", got, want)
+	}
+
+	reports[1].Errors = []string{"failed to delete tag v4"}
+	if got := GitHubOutputs(nil, reports...); !strings.Contains(got, "status=failure\n") {
+		t.Errorf("GitHubOutputs() with a deletion error =\n%s\nwant status=failure", got)
+	}
+}
@@ -0,0 +1,47 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+)
+
+// metricPrefix is prepended to all exported metric names
+const metricPrefix = "dockerhubcleaner"
+
+// Metrics renders the report in Prometheus text exposition format
+func (r *Report) Metrics() string {
+	return Metrics(r)
+}
+
+// Metrics renders one or more reports in Prometheus text exposition format,
+// with one sample per repository under each metric
+func Metrics(reports ...*Report) string {
+	var b strings.Builder
+
+	gauge := func(name, help string, value func(r *Report) int64) {
+		fmt.Fprintf(&b, "# HELP %s_%s %s\n", metricPrefix, name, help)
+		fmt.Fprintf(&b, "# TYPE %s_%s gauge\n", metricPrefix, name)
+		for _, r := range reports {
+			fmt.Fprintf(&b, "%s_%s{repository=%q} %d\n", metricPrefix, name, r.Repository, value(r))
+		}
+	}
+
+	gauge("tags_total", "Total number of tags in the repository.",
+		func(r *Report) int64 { return int64(r.TotalTags) })
+	gauge("tags_deleted", "Number of tags deleted (or that would be deleted in dry-run).",
+		func(r *Report) int64 { return int64(len(r.DeletedTags)) })
+	gauge("tags_kept", "Number of tags kept by the retention policy.",
+		func(r *Report) int64 { return int64(r.KeptTags) })
+	gauge("reclaimed_bytes", "Bytes reclaimed by deleted tags.",
+		func(r *Report) int64 { return r.ReclaimedSize })
+	gauge("errors_total", "Number of errors during the run.",
+		func(r *Report) int64 { return int64(len(r.Errors)) })
+
+	return b.String()
+}
+
+// WriteMetricsFile writes the metrics of one or more reports to path atomically
+// so textfile collectors never read a partial file
+func WriteMetricsFile(path string, reports ...*Report) error {
+	return WriteFile(path, []byte(Metrics(reports...)))
+}
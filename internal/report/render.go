@@ -0,0 +1,104 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/cleaner"
+)
+
+// Renderer writes the summary of a cleaning run in one output format
+type Renderer interface {
+	Render(w io.Writer, result *cleaner.CleanResult) error
+}
+
+// Flusher is implemented by renderers that write their output once all
+// repositories of a run are done
+type Flusher interface {
+	Flush(w io.Writer) error
+}
+
+// RenderConfig holds the run settings that affect how summaries read
+type RenderConfig struct {
+	DryRun        bool
+	PruneUntagged bool // report untagged manifests
+	AllowLatest   bool // tags sharing latest's image were deleted, not spared
+	ShowRepoInfo  bool // print the repository metadata header
+}
+
+// NewRenderer returns the renderer for an output format
+func NewRenderer(format string, cfg RenderConfig) (Renderer, error) {
+	switch format {
+	case "text":
+		return &TextRenderer{cfg: cfg}, nil
+	case "table":
+		return &TableRenderer{TextRenderer{cfg: cfg}}, nil
+	case "jsonl":
+		return &JSONLRenderer{dryRun: cfg.DryRun}, nil
+	case "json":
+		return &JSONRenderer{dryRun: cfg.DryRun}, nil
+	default:
+		return nil, fmt.Errorf("invalid output format %q: must be text, table, json or jsonl", format)
+	}
+}
+
+// JSONLRenderer writes the summary as a single JSON line
+type JSONLRenderer struct {
+	dryRun bool
+}
+
+// Render implements Renderer
+func (r *JSONLRenderer) Render(w io.Writer, result *cleaner.CleanResult) error {
+	data, err := New(result.Repository, r.dryRun, result).JSON()
+	if err != nil {
+		return fmt.Errorf("failed to encode summary: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// Detailed is a Report with the outcome of every filtered tag
+type Detailed struct {
+	*Report
+	Tags []cleaner.TagAction `json:"tags"`
+}
+
+// JSONRenderer collects the results of a run and writes them as one JSON
+// document on Flush: an object for a single repository, or an array of
+// objects sorted by repository
+type JSONRenderer struct {
+	dryRun bool
+	docs   []*Detailed
+}
+
+// Render implements Renderer; the result is written by Flush
+func (r *JSONRenderer) Render(w io.Writer, result *cleaner.CleanResult) error {
+	tags := result.Actions
+	if tags == nil {
+		tags = []cleaner.TagAction{}
+	}
+	r.docs = append(r.docs, &Detailed{Report: New(result.Repository, r.dryRun, result), Tags: tags})
+	return nil
+}
+
+// Flush implements Flusher
+func (r *JSONRenderer) Flush(w io.Writer) error {
+	var doc any = r.docs
+	switch len(r.docs) {
+	case 0:
+		return nil
+	case 1:
+		doc = r.docs[0]
+	default:
+		sort.Slice(r.docs, func(i, j int) bool { return r.docs[i].Repository < r.docs[j].Repository })
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode summary: %w", err)
+	}
+	return nil
+}
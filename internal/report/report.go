@@ -0,0 +1,86 @@
+package report
+
+import (
+	"encoding/json"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/cleaner"
+	"github.com/ataraskov/docker-hub-cleaner/internal/policy"
+	"github.com/ataraskov/docker-hub-cleaner/internal/snapshot"
+)
+
+// Report is the serializable form of a cleaning run
+type Report struct {
+	Repository    string             `json:"repository"`
+	DryRun        bool               `json:"dry_run"`
+	TotalTags     int                `json:"total_tags"`
+	FilteredTags  int                `json:"filtered_tags"`
+	KeptTags      int                `json:"kept_tags"`
+	DeletedTags   []string           `json:"deleted_tags"`
+	TotalSize     int64              `json:"total_size"`
+	ReclaimedSize int64              `json:"reclaimed_size"`
+	RealisticSize *int64             `json:"realistic_reclaimed_size,omitempty"`
+	Errors        []string           `json:"errors"`
+	PolicyCounts  []policy.KeepCount `json:"policy_counts"`
+	SharedTags    []string           `json:"shared_tags,omitempty"`
+	LatestTags    []string           `json:"latest_tags,omitempty"`
+	FloorTags     []string           `json:"min_keep_tags,omitempty"`
+	Largest       []cleaner.TagSize  `json:"largest,omitempty"`
+	Remaining     []string           `json:"remaining_tags,omitempty"`
+	DanglingTags  []string           `json:"dangling_tags,omitempty"`
+	Untagged      []string           `json:"untagged_manifests,omitempty"`
+	Changes       *snapshot.Diff     `json:"changes,omitempty"`
+	Unverified    []string           `json:"unverified,omitempty"`
+	Deferred      []string           `json:"deferred_tags,omitempty"`
+	Declined      []string           `json:"declined_tags,omitempty"`
+}
+
+// New builds a report from a cleaning result
+func New(repo string, dryRun bool, result *cleaner.CleanResult) *Report {
+	r := &Report{
+		Repository:    repo,
+		DryRun:        dryRun,
+		TotalTags:     result.TotalTags,
+		FilteredTags:  result.FilteredTags,
+		KeptTags:      result.KeptTags,
+		DeletedTags:   result.DeletedTags,
+		TotalSize:     result.TotalSize,
+		ReclaimedSize: result.ReclaimedSize,
+		Errors:        []string{},
+		PolicyCounts:  result.PolicyCounts,
+		SharedTags:    result.SharedTags,
+		LatestTags:    result.LatestTags,
+		FloorTags:     result.FloorTags,
+		Largest:       result.Largest,
+		Remaining:     result.KeptTagNames,
+		DanglingTags:  result.DanglingTags,
+		Untagged:      result.Untagged,
+		Changes:       result.Changes,
+		Unverified:    result.Unverified,
+		Deferred:      result.Deferred,
+		Declined:      result.Declined,
+	}
+
+	if result.RealisticSize >= 0 {
+		size := result.RealisticSize
+		r.RealisticSize = &size
+	}
+
+	if r.PolicyCounts == nil {
+		r.PolicyCounts = []policy.KeepCount{}
+	}
+
+	if r.DeletedTags == nil {
+		r.DeletedTags = []string{}
+	}
+
+	for _, err := range result.Errors {
+		r.Errors = append(r.Errors, err.Error())
+	}
+
+	return r
+}
+
+// JSON returns the report encoded as JSON
+func (r *Report) JSON() ([]byte, error) {
+	return json.Marshal(r)
+}
@@ -0,0 +1,248 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+	"github.com/ataraskov/docker-hub-cleaner/internal/cleaner"
+)
+
+const rule = "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"
+
+// TextRenderer writes the human-readable summary box
+type TextRenderer struct {
+	cfg RenderConfig
+}
+
+// Render implements Renderer
+func (r *TextRenderer) Render(w io.Writer, result *cleaner.CleanResult) error {
+	if r.cfg.ShowRepoInfo {
+		writeRepoInfo(w, result.Repository, result.RepoInfo)
+	}
+	r.writeSummary(w, result)
+	return nil
+}
+
+// writeSummary prints the run summary
+func (r *TextRenderer) writeSummary(w io.Writer, result *cleaner.CleanResult) {
+	verb, untaggedVerb := "deleted", "deleted"
+	if r.cfg.DryRun {
+		verb, untaggedVerb = "would delete", "to delete"
+	}
+
+	fmt.Fprintln(w, "\n"+rule)
+	fmt.Fprintln(w, "SUMMARY")
+	fmt.Fprintln(w, rule)
+	fmt.Fprintf(w, "Repository:       %s\n", result.Repository)
+	fmt.Fprintf(w, "Total tags:       %d\n", result.TotalTags)
+	fmt.Fprintf(w, "After filtering:  %d\n", result.FilteredTags)
+	fmt.Fprintf(w, "Tags to keep:     %d\n", result.KeptTags)
+	fmt.Fprintf(w, "Tags %s:  %d\n", verb, len(result.DeletedTags))
+
+	if len(result.DeletedTags) > 0 {
+		fmt.Fprintf(w, "Disk space:       %s\n", cleaner.FormatSize(result.ReclaimedSize))
+		if result.RealisticSize >= 0 {
+			fmt.Fprintf(w, "Realistic space:  %s (layers not shared with kept tags)\n", cleaner.FormatSize(result.RealisticSize))
+		}
+	}
+
+	if len(result.Deferred) > 0 {
+		fmt.Fprintf(w, "Deferred:         %d (over --max-delete, left for a later run)\n", len(result.Deferred))
+		for _, name := range result.Deferred {
+			fmt.Fprintf(w, "  - %s\n", name)
+		}
+	}
+
+	if len(result.Declined) > 0 {
+		fmt.Fprintf(w, "Declined:         %d (not approved with --interactive)\n", len(result.Declined))
+		for _, name := range result.Declined {
+			fmt.Fprintf(w, "  - %s\n", name)
+		}
+	}
+
+	if r.cfg.PruneUntagged {
+		fmt.Fprintf(w, "Untagged %s:  %d\n", untaggedVerb, len(result.Untagged))
+		if len(result.DanglingTags) > 0 {
+			fmt.Fprintf(w, "Tags w/o images:  %d\n", len(result.DanglingTags))
+		}
+	}
+
+	if result.Changes != nil {
+		fmt.Fprintf(w, "Since last run:   +%d / -%d tags (snapshot %s)\n",
+			len(result.Changes.Added), len(result.Changes.Removed), result.Changes.Since.Format("2006-01-02 15:04"))
+		for _, name := range result.Changes.Added {
+			fmt.Fprintf(w, "  + %s\n", name)
+		}
+		for _, name := range result.Changes.Removed {
+			fmt.Fprintf(w, "  - %s\n", name)
+		}
+	}
+
+	if len(result.KeptTagNames) > 0 {
+		fmt.Fprintf(w, "Remaining tags:   %d\n", len(result.KeptTagNames))
+		for _, name := range result.KeptTagNames {
+			fmt.Fprintf(w, "  - %s\n", name)
+		}
+	}
+
+	if len(result.Largest) > 0 {
+		fmt.Fprintf(w, "Largest tags:\n")
+		for _, t := range result.Largest {
+			fmt.Fprintf(w, "  - %-40s %s\n", t.Name, cleaner.FormatSize(t.Size))
+		}
+	}
+
+	if len(result.LatestTags) > 0 {
+		verb := "spared"
+		if r.cfg.AllowLatest {
+			verb = "deleted"
+		}
+		fmt.Fprintf(w, "Latest image:     %d %s (tags pointing at the image of latest)\n", len(result.LatestTags), verb)
+		for _, name := range result.LatestTags {
+			fmt.Fprintf(w, "  - %s\n", name)
+		}
+	}
+
+	if len(result.FloorTags) > 0 {
+		fmt.Fprintf(w, "Min-keep floor:   %d spared (among the newest --min-keep tags)\n", len(result.FloorTags))
+		for _, name := range result.FloorTags {
+			fmt.Fprintf(w, "  - %s\n", name)
+		}
+	}
+
+	if len(result.Unverified) > 0 {
+		fmt.Fprintf(w, "Still listed:     %d (deleted but not gone yet)\n", len(result.Unverified))
+		for _, name := range result.Unverified {
+			fmt.Fprintf(w, "  - %s\n", name)
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		fmt.Fprintf(w, "Errors:           %d\n", len(result.Errors))
+		for _, err := range result.Errors {
+			fmt.Fprintf(w, "  - %s\n", err)
+		}
+	}
+
+	if r.cfg.DryRun && len(result.DeletedTags) > 0 {
+		fmt.Fprintln(w, "\nRun without --dry-run to execute deletion.")
+	}
+
+	fmt.Fprintln(w, rule)
+}
+
+// TableRenderer writes one row per tag followed by the text summary
+type TableRenderer struct {
+	TextRenderer
+}
+
+// Render implements Renderer
+func (r *TableRenderer) Render(w io.Writer, result *cleaner.CleanResult) error {
+	if r.cfg.ShowRepoInfo {
+		writeRepoInfo(w, result.Repository, result.RepoInfo)
+	}
+	fmt.Fprintln(w)
+	if err := writeTable(w, result.Actions); err != nil {
+		return fmt.Errorf("failed to print table: %w", err)
+	}
+	r.writeSummary(w, result)
+	return nil
+}
+
+// WriteNamespaceSummary prints the combined result of a run over one or
+// more namespaces, grouped by namespace
+// unlisted names the namespaces whose repositories could not be listed
+func WriteNamespaceSummary(w io.Writer, namespaces []string, dryRun bool, reports []*Report, failed, unlisted []string) {
+	verb := "deleted"
+	if dryRun {
+		verb = "would delete"
+	}
+
+	fmt.Fprintln(w, "\n"+rule)
+	fmt.Fprintln(w, "NAMESPACE SUMMARY")
+	fmt.Fprintln(w, rule)
+	fmt.Fprintf(w, "Namespaces:       %s\n", strings.Join(namespaces, ", "))
+	fmt.Fprintf(w, "Repositories:     %d matched, %d processed, %d failed\n", len(reports)+len(failed), len(reports), len(failed))
+
+	var deleted int
+	var reclaimed int64
+	for _, ns := range namespaces {
+		fmt.Fprintf(w, "%s:\n", ns)
+		if slices.Contains(unlisted, ns) {
+			fmt.Fprintln(w, "  (failed to list repositories)")
+			continue
+		}
+		for _, rep := range reports {
+			if namespaceOf(rep.Repository) != ns {
+				continue
+			}
+			deleted += len(rep.DeletedTags)
+			reclaimed += rep.ReclaimedSize
+			fmt.Fprintf(w, "  - %-40s %d %s, %s\n", rep.Repository, len(rep.DeletedTags), verb, cleaner.FormatSize(rep.ReclaimedSize))
+		}
+		for _, repo := range failed {
+			if namespaceOf(repo) == ns {
+				fmt.Fprintf(w, "  - %-40s failed\n", repo)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "Tags %s:  %d\n", verb, deleted)
+	fmt.Fprintf(w, "Disk space:       %s\n", cleaner.FormatSize(reclaimed))
+	fmt.Fprintln(w, rule)
+}
+
+// namespaceOf returns the namespace part of a namespace/repo name
+func namespaceOf(repo string) string {
+	ns, _, _ := strings.Cut(repo, "/")
+	return ns
+}
+
+// writeRepoInfo prints the repository header, or just the name if the
+// metadata was unavailable
+func writeRepoInfo(w io.Writer, repo string, info *api.Repository) {
+	fmt.Fprintln(w, "\n"+rule)
+	fmt.Fprintln(w, "REPOSITORY")
+	fmt.Fprintln(w, rule)
+	fmt.Fprintf(w, "Name:             %s\n", repo)
+	if info == nil {
+		fmt.Fprintln(w, "(metadata unavailable)")
+		return
+	}
+	fmt.Fprintf(w, "Namespace:        %s\n", info.Namespace)
+	if info.Description != "" {
+		fmt.Fprintf(w, "Description:      %s\n", info.Description)
+	}
+	fmt.Fprintf(w, "Pulls:            %d\n", info.PullCount)
+	fmt.Fprintf(w, "Stars:            %d\n", info.StarCount)
+	if !info.LastUpdated.IsZero() {
+		fmt.Fprintf(w, "Last updated:     %s (%s ago)\n", info.LastUpdated.Format(time.DateOnly), formatAge(time.Since(info.LastUpdated)))
+	}
+}
+
+// writeTable writes one aligned row per tag in sort order
+func writeTable(w io.Writer, actions []cleaner.TagAction) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TAG\tAGE\tSIZE\tACTION")
+	now := time.Now()
+	for _, a := range actions {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", a.Name, formatAge(now.Sub(a.Updated)), cleaner.FormatSize(a.Size), a.Action)
+	}
+	return tw.Flush()
+}
+
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
@@ -0,0 +1,43 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WebhookTimeout bounds how long a webhook POST may take
+const WebhookTimeout = 10 * time.Second
+
+// PostWebhook sends the report as JSON to the given URL
+func PostWebhook(ctx context.Context, url string, r *Report) error {
+	body, err := r.JSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, WebhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event", "docker-hub-cleaner")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// timeFormat is used in snapshot file names and sorts chronologically
+const timeFormat = "20060102T150405Z"
+
+// Entry records a single tag in a snapshot
+type Entry struct {
+	Name        string    `json:"name"`
+	LastUpdated time.Time `json:"last_updated"`
+}
+
+// Snapshot is the tag list of a repository at a point in time
+type Snapshot struct {
+	Repository string    `json:"repository"`
+	Taken      time.Time `json:"taken"`
+	Tags       []Entry   `json:"tags"`
+}
+
+// Diff lists tags added and removed between two snapshots
+type Diff struct {
+	Since   time.Time `json:"since"`
+	Added   []string  `json:"added"`
+	Removed []string  `json:"removed"`
+}
+
+// New creates a snapshot of the given tags
+func New(repo string, tags []api.Tag) *Snapshot {
+	s := &Snapshot{
+		Repository: repo,
+		Taken:      time.Now().UTC(),
+		Tags:       make([]Entry, 0, len(tags)),
+	}
+	for _, tag := range tags {
+		s.Tags = append(s.Tags, Entry{Name: tag.Name, LastUpdated: tag.LastUpdated})
+	}
+	return s
+}
+
+// filePrefix returns the snapshot file name prefix for a repository
+func filePrefix(repo string) string {
+	return strings.ReplaceAll(repo, "/", "_") + "-"
+}
+
+// Write stores the snapshot in dir as a timestamped JSON file
+func Write(dir string, s *Snapshot) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	path := filepath.Join(dir, filePrefix(s.Repository)+s.Taken.Format(timeFormat)+".json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	return path, nil
+}
+
+// Latest loads the most recent snapshot for repo in dir
+// Returns nil without error if there is none
+func Latest(dir, repo string) (*Snapshot, error) {
+	prefix := filePrefix(repo)
+	candidates, err := filepath.Glob(filepath.Join(dir, prefix+"*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	// The glob also matches repositories whose name extends this one
+	// (org/app-api for org/app), so only a timestamp may follow the prefix
+	var matches []string
+	for _, path := range candidates {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), prefix), ".json")
+		if _, err := time.Parse(timeFormat, stamp); err == nil {
+			matches = append(matches, path)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	sort.Strings(matches)
+	data, err := os.ReadFile(matches[len(matches)-1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	return &s, nil
+}
+
+// Compare computes the tags added and removed since prev
+func Compare(prev, cur *Snapshot) *Diff {
+	d := &Diff{
+		Since:   prev.Taken,
+		Added:   []string{},
+		Removed: []string{},
+	}
+
+	before := make(map[string]bool, len(prev.Tags))
+	for _, e := range prev.Tags {
+		before[e.Name] = true
+	}
+
+	now := make(map[string]bool, len(cur.Tags))
+	for _, e := range cur.Tags {
+		now[e.Name] = true
+		if !before[e.Name] {
+			d.Added = append(d.Added, e.Name)
+		}
+	}
+
+	for _, e := range prev.Tags {
+		if !now[e.Name] {
+			d.Removed = append(d.Removed, e.Name)
+		}
+	}
+
+	return d
+}
@@ -0,0 +1,36 @@
+package snapshot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+func TestLatestIgnoresOtherRepositories(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	app := New("org/app", []api.Tag{{Name: "v1"}})
+	app.Taken = base
+	// A newer snapshot of a repository whose name starts like org/app
+	other := New("org/app-api", []api.Tag{{Name: "other"}})
+	other.Taken = base.Add(time.Hour)
+	for _, s := range []*Snapshot{app, other} {
+		if _, err := Write(dir, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := Latest(dir, "org/app")
+	if err != nil {
+		t.Fatalf("Latest() error = %v", err)
+	}
+	if got == nil || got.Repository != "org/app" {
+		t.Fatalf("Latest() = %+v, want the org/app snapshot", got)
+	}
+
+	if got, err := Latest(dir, "org/ap"); err != nil || got != nil {
+		t.Errorf("Latest(org/ap) = %+v, %v, want none", got, err)
+	}
+}
@@ -0,0 +1,37 @@
+package sort
+
+import (
+	"sort"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// DateSorter sorts tags by timestamp (descending)
+type DateSorter struct {
+	field api.AgeField
+}
+
+// NewDateSorter creates a new date sorter
+// The field selects which tag timestamp is compared
+func NewDateSorter(field api.AgeField) *DateSorter {
+	return &DateSorter{
+		field: field,
+	}
+}
+
+// Sort sorts tags by timestamp in descending order (newest first)
+// Tags with equal timestamps are ordered by name (descending)
+func (s *DateSorter) Sort(tags []api.Tag) []api.Tag {
+	sorted := make([]api.Tag, len(tags))
+	copy(sorted, tags)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ti, tj := sorted[i].Time(s.field), sorted[j].Time(s.field)
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return sorted[i].Name > sorted[j].Name
+	})
+
+	return sorted
+}
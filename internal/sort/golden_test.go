@@ -0,0 +1,100 @@
+package sort
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenSorters are run over every fixture; each writes testdata/<fixture>.<name>.golden
+func goldenSorters(t *testing.T) map[string]TagSorter {
+	t.Helper()
+	semver, err := NewSemverSorter("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	numeric, err := NewNumericSorter("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]TagSorter{
+		"lexicographical": NewLexicographicalSorter(),
+		"semver":          semver,
+		"numeric":         numeric,
+		"date":            NewDateSorter(api.AgeFieldLastUpdated),
+	}
+}
+
+// TestGoldenSort sorts each testdata/*.json fixture (see api.ReadTags) with
+// every sorter and compares the order against its golden file
+// Run with -update after an intended change to regenerate the golden files
+func TestGoldenSort(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatal("no fixtures in testdata")
+	}
+
+	for _, fixture := range fixtures {
+		tags := readFixture(t, fixture)
+		for name, sorter := range goldenSorters(t) {
+			golden := strings.TrimSuffix(fixture, ".json") + "." + name + ".golden"
+			t.Run(filepath.Base(golden), func(t *testing.T) {
+				in := append([]api.Tag(nil), tags...)
+				compareGolden(t, golden, tagNames(sorter.Sort(in)))
+			})
+		}
+	}
+}
+
+// readFixture loads the tags of a JSON fixture
+func readFixture(t *testing.T, path string) []api.Tag {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tags, err := api.ReadTags(f)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return tags
+}
+
+// tagNames renders tag names one per line
+func tagNames(tags []api.Tag) []byte {
+	var b bytes.Buffer
+	for _, tag := range tags {
+		b.WriteString(tag.Name)
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// compareGolden compares got with the golden file, rewriting it with -update
+func compareGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s mismatch (run with -update to accept)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
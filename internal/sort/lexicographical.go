@@ -7,13 +7,21 @@ import (
 )
 
 // LexicographicalSorter sorts tags lexicographically (descending)
-type LexicographicalSorter struct{}
+type LexicographicalSorter struct {
+	normalize NameTransform // optional: rewrite names before comparison
+}
 
 // NewLexicographicalSorter creates a new lexicographical sorter
 func NewLexicographicalSorter() *LexicographicalSorter {
 	return &LexicographicalSorter{}
 }
 
+// WithNormalize sets a transform applied to tag names before comparison
+func (s *LexicographicalSorter) WithNormalize(t NameTransform) *LexicographicalSorter {
+	s.normalize = t
+	return s
+}
+
 // Sort sorts tags lexicographically in descending order (newest first)
 func (s *LexicographicalSorter) Sort(tags []api.Tag) []api.Tag {
 	sorted := make([]api.Tag, len(tags))
@@ -21,7 +29,7 @@ func (s *LexicographicalSorter) Sort(tags []api.Tag) []api.Tag {
 
 	sort.Slice(sorted, func(i, j int) bool {
 		// Descending order (newest first)
-		return sorted[i].Name > sorted[j].Name
+		return s.normalize.apply(sorted[i].Name) > s.normalize.apply(sorted[j].Name)
 	})
 
 	return sorted
@@ -0,0 +1,37 @@
+package sort
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// NameTransform rewrites a tag name into the view used for comparison
+// It never changes the tag itself; deletion always uses the original name
+type NameTransform func(name string) string
+
+// NewRegexReplace parses a "pattern=>replacement" rule into a NameTransform
+// The replacement may reference capture groups (e.g., "$1")
+func NewRegexReplace(rule string) (NameTransform, error) {
+	pattern, replacement, ok := strings.Cut(rule, "=>")
+	if !ok {
+		return nil, fmt.Errorf("invalid normalize rule %q (expected pattern=>replacement)", rule)
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile normalize pattern: %w", err)
+	}
+
+	return func(name string) string {
+		return re.ReplaceAllString(name, replacement)
+	}, nil
+}
+
+// apply applies a transform, treating nil as the identity
+func (t NameTransform) apply(name string) string {
+	if t == nil {
+		return name
+	}
+	return t(name)
+}
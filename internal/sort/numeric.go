@@ -0,0 +1,115 @@
+package sort
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// numericDotted matches versions made of dot-separated numbers with an
+// optional "v" prefix, e.g. "20240615.1.2", "1.2.3.4" or "2024.06.15"
+var numericDotted = regexp.MustCompile(`^v?[0-9]+(\.[0-9]+)*$`)
+
+// NumericSorter sorts tags by dot-separated numeric versions that are not
+// strict semver (extra fields, leading zeros); numbers of any size compare
+// by value
+type NumericSorter struct {
+	stripPrefixPattern *regexp.Regexp // optional: strip custom prefix before parsing
+	normalize          NameTransform  // optional: rewrite names before stripping
+}
+
+// NewNumericSorter creates a new numeric-dotted sorter
+func NewNumericSorter(stripPrefixPattern string) (*NumericSorter, error) {
+	s := &NumericSorter{}
+
+	if stripPrefixPattern != "" {
+		re, err := regexp.Compile(stripPrefixPattern)
+		if err != nil {
+			return nil, err
+		}
+		s.stripPrefixPattern = re
+	}
+
+	return s, nil
+}
+
+// WithNormalize sets a transform applied to tag names before prefix stripping
+func (s *NumericSorter) WithNormalize(t NameTransform) *NumericSorter {
+	s.normalize = t
+	return s
+}
+
+// fields returns the numeric fields of a tag name, or false if the name
+// (after normalizing and stripping the prefix) is not numeric-dotted
+func (s *NumericSorter) fields(name string) ([]string, bool) {
+	v := s.normalize.apply(name)
+	if s.stripPrefixPattern != nil {
+		v = s.stripPrefixPattern.ReplaceAllString(v, "")
+	}
+	if !numericDotted.MatchString(v) {
+		return nil, false
+	}
+	return strings.Split(strings.TrimPrefix(v, "v"), "."), true
+}
+
+// compareNumeric compares two numeric-dotted versions field by field;
+// missing fields count as 0, so "1.2" equals "1.2.0"
+func compareNumeric(a, b []string) int {
+	for i := 0; i < max(len(a), len(b)); i++ {
+		x, y := "0", "0"
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if c := compareDigits(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareDigits compares two decimal numbers of arbitrary length
+func compareDigits(x, y string) int {
+	x, y = strings.TrimLeft(x, "0"), strings.TrimLeft(y, "0")
+	if len(x) != len(y) {
+		if len(x) < len(y) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(x, y)
+}
+
+// Sort sorts numeric-dotted tags by version (descending - newest first),
+// followed by the remaining tags lexicographically (descending)
+// Tags of equal version are ordered by name (descending)
+func (s *NumericSorter) Sort(tags []api.Tag) []api.Tag {
+	var numericTags, otherTags []api.Tag
+	versions := make(map[string][]string, len(tags))
+
+	for _, tag := range tags {
+		if f, ok := s.fields(tag.Name); ok {
+			versions[tag.Name] = f
+			numericTags = append(numericTags, tag)
+		} else {
+			otherTags = append(otherTags, tag)
+		}
+	}
+
+	sort.SliceStable(numericTags, func(i, j int) bool {
+		if c := compareNumeric(versions[numericTags[i].Name], versions[numericTags[j].Name]); c != 0 {
+			return c > 0
+		}
+		return numericTags[i].Name > numericTags[j].Name
+	})
+
+	sort.Slice(otherTags, func(i, j int) bool {
+		return otherTags[i].Name > otherTags[j].Name
+	})
+
+	return append(numericTags, otherTags...)
+}
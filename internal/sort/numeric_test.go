@@ -0,0 +1,57 @@
+package sort
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+func TestNumericSortLargeNumbers(t *testing.T) {
+	tests := []struct {
+		name  string
+		strip string
+		tags  []string
+		want  []string
+	}{
+		{
+			// The date field decides before the smaller fields
+			name: "date first",
+			tags: []string{"20240614.9.9", "20240615.1.2"},
+			want: []string{"20240615.1.2", "20240614.9.9"},
+		},
+		{
+			name: "by value, not by text",
+			tags: []string{"20240614.9.9", "20240615.1.10", "20240615.1.2", "v20240616", "20240615.01.3", "main"},
+			want: []string{"v20240616", "20240615.1.10", "20240615.01.3", "20240615.1.2", "20240614.9.9", "main"},
+		},
+		{
+			// Beyond 64 bits
+			name: "arbitrary size",
+			tags: []string{"18446744073709551616.1", "18446744073709551615.9", "99999999999999999999999.0"},
+			want: []string{"99999999999999999999999.0", "18446744073709551616.1", "18446744073709551615.9"},
+		},
+		{
+			name:  "prefixed",
+			strip: "^build-",
+			tags:  []string{"build-20240614.9.9", "build-20240615.1.2", "20240613.0.0"},
+			want:  []string{"build-20240615.1.2", "build-20240614.9.9", "20240613.0.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewNumericSorter(tt.strip)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tags := make([]api.Tag, len(tt.tags))
+			for i, name := range tt.tags {
+				tags[i] = api.Tag{Name: name}
+			}
+			if got := names(s.Sort(tags)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Sort() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,9 +9,25 @@ import (
 	"golang.org/x/mod/semver"
 )
 
+// Tiebreak selects how semver tags of equal precedence are ordered
+// (e.g. "1.2.3+build1" and "1.2.3+build2", or "1.2.3" and "v1.2.3")
+type Tiebreak string
+
+const (
+	// TiebreakDate puts the most recently updated tag first, then orders by name
+	TiebreakDate Tiebreak = "date"
+	// TiebreakName orders by name only (descending)
+	TiebreakName Tiebreak = "name"
+)
+
 // SemverSorter sorts tags using semantic versioning
 type SemverSorter struct {
 	stripPrefixPattern *regexp.Regexp // optional: strip custom prefix before parsing
+	normalize          NameTransform  // optional: rewrite names before stripping
+	fallback           TagSorter      // optional: orders non-semver tags (default: by name)
+	tiebreak           Tiebreak       // default: TiebreakDate
+	dateField          api.AgeField   // timestamp used by TiebreakDate
+	prereleaseFirst    bool           // order prereleases ahead of their release
 }
 
 // NewSemverSorter creates a new semver sorter
@@ -29,8 +45,38 @@ func NewSemverSorter(stripPrefixPattern string) (*SemverSorter, error) {
 	return s, nil
 }
 
-// stripPrefix removes custom prefix if pattern is set
+// WithFallback sets the sorter used for tags that are not valid semver
+// They are still placed after all semver tags
+func (s *SemverSorter) WithFallback(t TagSorter) *SemverSorter {
+	s.fallback = t
+	return s
+}
+
+// WithTiebreak sets how tags of equal precedence are ordered
+// The field selects the timestamp compared by TiebreakDate
+func (s *SemverSorter) WithTiebreak(t Tiebreak, field api.AgeField) *SemverSorter {
+	s.tiebreak = t
+	s.dateField = field
+	return s
+}
+
+// WithPrereleaseFirst orders prereleases ahead of the release they precede
+// (1.2.3-rc2, 1.2.3-rc1, 1.2.3), so the latest release candidate counts as
+// newer than the release it was cut for
+func (s *SemverSorter) WithPrereleaseFirst() *SemverSorter {
+	s.prereleaseFirst = true
+	return s
+}
+
+// WithNormalize sets a transform applied to tag names before prefix stripping
+func (s *SemverSorter) WithNormalize(t NameTransform) *SemverSorter {
+	s.normalize = t
+	return s
+}
+
+// stripPrefix normalizes the name and removes custom prefix if pattern is set
 func (s *SemverSorter) stripPrefix(v string) string {
+	v = s.normalize.apply(v)
 	if s.stripPrefixPattern != nil {
 		return s.stripPrefixPattern.ReplaceAllString(v, "")
 	}
@@ -45,6 +91,37 @@ func normalizeVersion(v string) string {
 	return v
 }
 
+// Version returns the normalized semver string for a tag name
+// (custom prefix stripped, "v" prefix added). The result may not be valid semver.
+func (s *SemverSorter) Version(name string) string {
+	return normalizeVersion(s.stripPrefix(name))
+}
+
+// release returns the canonical major.minor.patch of a valid version
+func release(v string) string {
+	return strings.TrimSuffix(semver.Canonical(v), semver.Prerelease(v))
+}
+
+// Classification describes how a tag name is transformed before semver parsing
+type Classification struct {
+	Original string // tag name as pushed
+	Stripped string // after normalizing and removing the custom prefix
+	Version  string // after normalizing with a "v" prefix
+	Valid    bool   // whether Version is valid semver
+}
+
+// Classify exposes each transformation step applied to a tag name
+func (s *SemverSorter) Classify(name string) Classification {
+	stripped := s.stripPrefix(name)
+	version := normalizeVersion(stripped)
+	return Classification{
+		Original: name,
+		Stripped: stripped,
+		Version:  version,
+		Valid:    semver.IsValid(version),
+	}
+}
+
 // Sort sorts tags using semantic version comparison
 func (s *SemverSorter) Sort(tags []api.Tag) []api.Tag {
 	var semverTags, nonSemverTags []api.Tag
@@ -63,17 +140,37 @@ func (s *SemverSorter) Sort(tags []api.Tag) []api.Tag {
 	}
 
 	// Sort semver tags using semver.Compare (descending - newest first)
-	sort.Slice(semverTags, func(i, j int) bool {
+	sort.SliceStable(semverTags, func(i, j int) bool {
 		v1 := normalizeVersion(s.stripPrefix(semverTags[i].Name))
 		v2 := normalizeVersion(s.stripPrefix(semverTags[j].Name))
+		if s.prereleaseFirst {
+			pre1, pre2 := semver.Prerelease(v1) != "", semver.Prerelease(v2) != ""
+			if pre1 != pre2 && release(v1) == release(v2) {
+				return pre1
+			}
+		}
 		// Descending order: v2 < v1 means v1 comes first
-		return semver.Compare(v1, v2) > 0
+		if c := semver.Compare(v1, v2); c != 0 {
+			return c > 0
+		}
+		// Equal precedence (build metadata, "1.2.3" vs "v1.2.3"): newest update first, then by name
+		if s.tiebreak != TiebreakName {
+			t1, t2 := semverTags[i].Time(s.dateField), semverTags[j].Time(s.dateField)
+			if !t1.Equal(t2) {
+				return t1.After(t2)
+			}
+		}
+		return semverTags[i].Name > semverTags[j].Name
 	})
 
-	// Sort non-semver lexicographically (descending)
-	sort.Slice(nonSemverTags, func(i, j int) bool {
-		return nonSemverTags[i].Name > nonSemverTags[j].Name
-	})
+	// Sort non-semver with the fallback sorter, or lexicographically (descending)
+	if s.fallback != nil {
+		nonSemverTags = s.fallback.Sort(nonSemverTags)
+	} else {
+		sort.Slice(nonSemverTags, func(i, j int) bool {
+			return nonSemverTags[i].Name > nonSemverTags[j].Name
+		})
+	}
 
 	// Return semver first, then non-semver
 	return append(semverTags, nonSemverTags...)
@@ -0,0 +1,180 @@
+package sort
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ataraskov/docker-hub-cleaner/internal/api"
+)
+
+// equalPrecedence returns tags that all share the precedence of 1.2.3
+func equalPrecedence() []api.Tag {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []api.Tag{
+		{Name: "1.2.3", LastUpdated: base.Add(2 * time.Hour)},
+		{Name: "v1.2.3", LastUpdated: base.Add(3 * time.Hour)},
+		{Name: "1.2.3+build1", LastUpdated: base.Add(1 * time.Hour)},
+		{Name: "1.2.3+build2", LastUpdated: base.Add(3 * time.Hour)},
+		{Name: "v1.2.3+build1", LastUpdated: base},
+		{Name: "1.2.4", LastUpdated: base},
+		{Name: "1.2.2", LastUpdated: base.Add(4 * time.Hour)},
+	}
+}
+
+func names(tags []api.Tag) []string {
+	out := make([]string, len(tags))
+	for i, tag := range tags {
+		out[i] = tag.Name
+	}
+	return out
+}
+
+func TestSemverSortTiebreak(t *testing.T) {
+	tests := []struct {
+		tiebreak Tiebreak
+		want     []string
+	}{
+		{
+			// Newest update first, then by name
+			tiebreak: TiebreakDate,
+			want:     []string{"1.2.4", "v1.2.3", "1.2.3+build2", "1.2.3", "1.2.3+build1", "v1.2.3+build1", "1.2.2"},
+		},
+		{
+			tiebreak: TiebreakName,
+			want:     []string{"1.2.4", "v1.2.3+build1", "v1.2.3", "1.2.3+build2", "1.2.3+build1", "1.2.3", "1.2.2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.tiebreak), func(t *testing.T) {
+			s, err := NewSemverSorter("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.WithTiebreak(tt.tiebreak, api.AgeFieldLastUpdated)
+
+			// The order must not depend on the input order
+			rng := rand.New(rand.NewSource(1))
+			for run := 0; run < 20; run++ {
+				tags := equalPrecedence()
+				rng.Shuffle(len(tags), func(i, j int) { tags[i], tags[j] = tags[j], tags[i] })
+				if got := names(s.Sort(tags)); !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("run %d: Sort() = %v, want %v", run, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// mixedHashes returns semver tags mixed with commit-hash tags; the hashes
+// were pushed in the order c0ffee1, a1b2c3d, f00ba47 (newest last)
+func mixedHashes() []api.Tag {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []api.Tag{
+		{Name: "a1b2c3d", LastUpdated: base.Add(2 * time.Hour)},
+		{Name: "1.2.0", LastUpdated: base},
+		{Name: "f00ba47", LastUpdated: base.Add(3 * time.Hour)},
+		{Name: "v1.10.0", LastUpdated: base.Add(1 * time.Hour)},
+		{Name: "c0ffee1", LastUpdated: base.Add(1 * time.Hour)},
+		{Name: "1.9.3", LastUpdated: base.Add(5 * time.Hour)},
+	}
+}
+
+func TestSemverSortMixedHashes(t *testing.T) {
+	semverFirst := []string{"v1.10.0", "1.9.3", "1.2.0"}
+	tests := []struct {
+		name     string
+		fallback TagSorter
+		want     []string
+	}{
+		{"default", nil, append(semverFirst, "f00ba47", "c0ffee1", "a1b2c3d")},
+		{"name", NewLexicographicalSorter(), append(semverFirst, "f00ba47", "c0ffee1", "a1b2c3d")},
+		{"date", NewDateSorter(api.AgeFieldLastUpdated), append(semverFirst, "f00ba47", "a1b2c3d", "c0ffee1")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSemverSorter("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.fallback != nil {
+				s.WithFallback(tt.fallback)
+			}
+			if got := names(s.Sort(mixedHashes())); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Sort() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// releaseCluster returns a release with its prereleases and build variants
+func releaseCluster() []api.Tag {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []api.Tag{
+		{Name: "1.3.0-beta", LastUpdated: base},
+		{Name: "1.2.3", LastUpdated: base.Add(3 * time.Hour)},
+		{Name: "1.2.3+b1", LastUpdated: base.Add(1 * time.Hour)},
+		{Name: "1.2.3-rc.2", LastUpdated: base},
+		{Name: "1.2.3-rc.1", LastUpdated: base.Add(5 * time.Hour)},
+		{Name: "1.2.3-rc.1+b2", LastUpdated: base.Add(2 * time.Hour)},
+		{Name: "1.2.2", LastUpdated: base.Add(6 * time.Hour)},
+	}
+}
+
+func TestSemverSortPrereleaseClusters(t *testing.T) {
+	tests := []struct {
+		name            string
+		prereleaseFirst bool
+		tiebreak        Tiebreak
+		want            []string
+	}{
+		{
+			name:     "release first by date",
+			tiebreak: TiebreakDate,
+			want:     []string{"1.3.0-beta", "1.2.3", "1.2.3+b1", "1.2.3-rc.2", "1.2.3-rc.1", "1.2.3-rc.1+b2", "1.2.2"},
+		},
+		{
+			name:     "release first by name",
+			tiebreak: TiebreakName,
+			want:     []string{"1.3.0-beta", "1.2.3+b1", "1.2.3", "1.2.3-rc.2", "1.2.3-rc.1+b2", "1.2.3-rc.1", "1.2.2"},
+		},
+		{
+			// Prereleases move above their own release, never past another version
+			name:            "prerelease first by date",
+			prereleaseFirst: true,
+			tiebreak:        TiebreakDate,
+			want:            []string{"1.3.0-beta", "1.2.3-rc.2", "1.2.3-rc.1", "1.2.3-rc.1+b2", "1.2.3", "1.2.3+b1", "1.2.2"},
+		},
+		{
+			name:            "prerelease first by name",
+			prereleaseFirst: true,
+			tiebreak:        TiebreakName,
+			want:            []string{"1.3.0-beta", "1.2.3-rc.2", "1.2.3-rc.1+b2", "1.2.3-rc.1", "1.2.3+b1", "1.2.3", "1.2.2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSemverSorter("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.WithTiebreak(tt.tiebreak, api.AgeFieldLastUpdated)
+			if tt.prereleaseFirst {
+				s.WithPrereleaseFirst()
+			}
+
+			rng := rand.New(rand.NewSource(1))
+			for run := 0; run < 20; run++ {
+				tags := releaseCluster()
+				rng.Shuffle(len(tags), func(i, j int) { tags[i], tags[j] = tags[j], tags[i] })
+				if got := names(s.Sort(tags)); !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("run %d: Sort() = %v, want %v", run, got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -7,3 +7,9 @@ type TagSorter interface {
 	// Sort sorts tags and returns them in the desired order
 	Sort(tags []api.Tag) []api.Tag
 }
+
+// Versioner is implemented by sorters that parse a version from tag names
+type Versioner interface {
+	// Version returns the version string parsed from a tag name
+	Version(name string) string
+}
@@ -0,0 +1,95 @@
+package state
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// State records successfully deleted tags so an interrupted run can resume
+// Each deletion is appended and flushed to disk as soon as it happens
+type State struct {
+	mu      sync.Mutex
+	file    *os.File
+	deleted map[string]bool
+}
+
+// Open loads the state file at path, creating it if it does not exist
+func Open(path string) (*State, error) {
+	s := &State{
+		deleted: make(map[string]bool),
+	}
+
+	f, err := os.Open(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to open state file: %w", err)
+	}
+	if err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" {
+				s.deleted[line] = true
+			}
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read state file: %w", err)
+		}
+	}
+
+	s.file, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state file for writing: %w", err)
+	}
+
+	return s, nil
+}
+
+// key builds the state entry for a repository tag
+func key(repo, tag string) string {
+	return repo + "\t" + tag
+}
+
+// Deleted returns true if the tag was recorded as deleted
+func (s *State) Deleted(repo, tag string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deleted[key(repo, tag)]
+}
+
+// Len returns the number of recorded deletions
+func (s *State) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.deleted)
+}
+
+// Record appends a deleted tag to the state file and flushes it to disk
+func (s *State) Record(repo, tag string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key(repo, tag)
+	if s.deleted[k] {
+		return nil
+	}
+
+	if _, err := s.file.WriteString(k + "\n"); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync state file: %w", err)
+	}
+
+	s.deleted[k] = true
+	return nil
+}
+
+// Close closes the underlying state file
+func (s *State) Close() error {
+	return s.file.Close()
+}